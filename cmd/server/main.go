@@ -1,18 +1,32 @@
 package main
 
 import (
-	"net/http"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/jwtauth"
 	"github.com/rafaelsouzaribeiro/9-API/configs"
 	_ "github.com/rafaelsouzaribeiro/9-API/docs"
 	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/buildinfo"
 	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database/migrations"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/mail"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/metrics"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/tracing"
 	"github.com/rafaelsouzaribeiro/9-API/internal/infra/webservice/handlers"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/webservice/middlewares"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/webservice/server"
 	httpSwagger "github.com/swaggo/http-swagger"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -30,53 +44,360 @@ import (
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey ApiKeyHeader
+// @in header
+// @name X-API-Key
 func main() {
-	config, err := configs.LoadConfig(".")
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	config, args, err := configs.LoadConfig(".", os.Args[1:])
+
+	if errors.Is(err, configs.ErrHelp) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Open(database.Config{
+		Driver:          config.DBDriver,
+		Host:            config.DBHost,
+		Port:            config.DBPort,
+		User:            config.DBUser,
+		Password:        config.DBPassword,
+		Name:            config.DBName,
+		SSLMode:         config.DBSSLMode,
+		MaxOpenConns:    config.DBMaxOpenConns,
+		MaxIdleConns:    config.DBMaxIdleConns,
+		ConnMaxLifetime: time.Second * time.Duration(config.DBConnMaxLifetime),
+	})
 
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	defer closeDatabase(db)
+
+	err = entity.ConfigurePasswords(entity.PasswordPolicy{
+		MinLength:     config.PasswordMinLength,
+		RequireUpper:  config.PasswordRequireUpper,
+		RequireLower:  config.PasswordRequireLower,
+		RequireDigit:  config.PasswordRequireDigit,
+		RequireSymbol: config.PasswordRequireSymbol,
+		RejectCommon:  config.PasswordRejectCommon,
+	}, config.BcryptCost)
+
+	if err != nil {
+		return fmt.Errorf("configurando senhas: %w", err)
+	}
+
+	if len(args) > 0 && args[0] == "migrate" {
+		return runMigrate(db, args[1:])
+	}
+
+	if len(args) > 0 && args[0] == "role" {
+		return runRole(db, args[1:])
+	}
+
+	if config.DBAutoMigrate {
+		if _, err := migrations.NewMigrator(db).Up(); err != nil {
+			return fmt.Errorf("aplicando migrations: %w", err)
+		}
+	}
+
+	build := buildinfo.Read()
+	appMetrics := metrics.New()
+
+	if err := appMetrics.InstrumentDB(db, config.DBDriver); err != nil {
+		return fmt.Errorf("instrumentando o banco: %w", err)
 	}
 
-	db, err := gorm.Open(sqlite.Open("teste.db"), &gorm.Config{})
+	tracerProvider, shutdownTracer, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    config.TracingExporter,
+		Endpoint:    config.TracingOTLPEndpoint,
+		SampleRatio: config.TracingSampleRatio,
+		ServiceName: config.TracingServiceName,
+		Version:     build.Version,
+	})
 
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("configurando tracing: %w", err)
 	}
 
-	db.AutoMigrate(&entity.Product{}, &entity.User{})
+	// Roda depois do servidor parar, para enviar os spans das últimas requisições
+	defer flushTraces(shutdownTracer)
+
+	if err := tracing.InstrumentDB(db, tracerProvider); err != nil {
+		return fmt.Errorf("instrumentando o banco: %w", err)
+	}
 
 	productDb := database.NewProduct(db)
 	productHandler := handlers.NewProductHandler(productDb)
 
 	userDb := database.NewUser(db)
-	userHandler := handlers.NewUserHandler(userDb)
+
+	// Sem JWT_KEYS_DIR os tokens continuam assinados em HS256 com o JWT_SECRET
+	tokenKeys := auth.NewHMACKeySet([]byte(config.JwtSecret))
+
+	if config.JwtKeysDir != "" {
+		tokenKeys, err = auth.LoadKeySet(config.JwtKeysDir, config.JwtSigningKid)
+
+		if err != nil {
+			return fmt.Errorf("carregando chaves JWT: %w", err)
+		}
+	}
+
+	tokenKeys.Validation = auth.TokenValidation{
+		Issuer:   config.JwtIssuer,
+		Audience: config.JwtAudience,
+		Leeway:   time.Second * time.Duration(config.JwtLeeway),
+	}
+
+	tokenService := auth.NewTokenService(
+		tokenKeys,
+		time.Second*time.Duration(config.JwtExpiresIn),
+		time.Second*time.Duration(config.JwtRefreshExpiresIn),
+		config.JwtIssuer,
+		config.JwtAudience,
+		userDb,
+		database.NewRefreshToken(db),
+		database.NewRevokedToken(db),
+	)
+
+	mailer, err := mail.New(config.MailDriver, config.MailDir)
+
+	if err != nil {
+		return fmt.Errorf("configurando e-mail: %w", err)
+	}
+
+	passwordService := auth.NewPasswordService(
+		userDb,
+		database.NewPasswordResetToken(db),
+		tokenService.RefreshTokens,
+		mailer,
+		config.MailFrom,
+		time.Second*time.Duration(config.PasswordResetExpiresIn),
+		config.PasswordResetURL,
+	)
+
+	verificationService := auth.NewVerificationService(
+		userDb,
+		auth.NewVerificationSigner([]byte(config.JwtSecret), time.Second*time.Duration(config.EmailVerificationExpiresIn)),
+		auth.NewMailNotifier(mailer, config.MailFrom, config.EmailVerificationURL),
+		config.EmailVerificationRequired,
+	)
+
+	lockoutBase := time.Second * time.Duration(config.LoginLockoutBase)
+	lockoutMax := time.Second * time.Duration(config.LoginLockoutMax)
+	loginService := auth.NewLoginService(
+		userDb,
+		auth.NewLoginLimiter(config.LoginMaxAttemptsPerEmail, lockoutBase, lockoutMax),
+		auth.NewLoginLimiter(config.LoginMaxAttemptsPerIP, lockoutBase, lockoutMax),
+	)
+
+	// Redefinição de senha e reenvio da verificação dividem a cota de cada e-mail
+	emailLimits := auth.NewEmailLimits(
+		auth.NewLoginLimiter(config.MailMaxRequestsPerEmail, lockoutBase, lockoutMax),
+		auth.NewLoginLimiter(config.MailMaxRequestsPerIP, lockoutBase, lockoutMax),
+	)
+	passwordService.Limits = emailLimits
+	verificationService.Limits = emailLimits
+
+	tokenService.Verification = verificationService
+
+	apiKeyService := auth.NewAPIKeyService(database.NewAPIKey(db), userDb)
+	apiKeyService.Verification = verificationService
+
+	userHandler := handlers.NewUserHandler(userDb, tokenService, passwordService, verificationService, loginService, apiKeyService)
+	userHandler.Metrics = appMetrics
+
+	oidcProviders := make([]*auth.OIDCProvider, 0, len(config.OIDCProviders))
+
+	for _, provider := range config.OIDCProviders {
+		oidcProviders = append(oidcProviders, auth.NewOIDCProvider(provider.Name, provider.Issuer, provider.ClientID, provider.ClientSecret, provider.RedirectURL, provider.Scopes))
+	}
+
+	oidcService := auth.NewOIDCService(
+		oidcProviders,
+		userDb,
+		database.NewUserIdentity(db),
+		tokenService.RefreshTokens,
+		apiKeyService.Keys,
+		auth.NewOIDCFlowSigner([]byte(config.JwtSecret)),
+	)
+	oidcHandler := handlers.NewOIDCHandler(oidcService, tokenService)
+
+	migrator := migrations.NewMigrator(db)
+	healthHandler := handlers.NewHealthHandler(build,
+		handlers.HealthCheck{Name: "database", Check: func(ctx context.Context) error { return database.Ping(ctx, db) }},
+		handlers.HealthCheck{Name: "migrations", Check: migrator.Current},
+	)
+
+	go purgeRevokedTokens(tokenService.RevokedTokens, time.Hour)
+	go purgeLoginAttempts(time.Minute, loginService.EmailLimits, loginService.IPLimits, emailLimits.Email, emailLimits.IP)
+
+	// O nível e as origens do CORS podem mudar com a recarga da configuração
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(config.SlogLevel())
+	requestLog := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+	corsOrigins := middlewares.NewCORSOrigins(config.CORSOrigins())
 
 	router := chi.NewRouter()
-	router.Use(middleware.Logger)
+	router.NotFound(handlers.NotFound)
+	router.MethodNotAllowed(handlers.MethodNotAllowed)
+	// As sondas e o Prometheus chamam a cada poucos segundos e só poluiriam o log e os traces
+	operational := []string{"/healthz", "/readyz", "/version", "/metrics"}
+
+	router.Use(middleware.RequestID)
+	router.Use(middlewares.CORS(corsOrigins))
+	router.Use(middlewares.SkipPaths(tracing.Middleware(tracerProvider), operational...))
+	router.Use(appMetrics.Middleware)
+	router.Use(middlewares.SkipPaths(middlewares.RequestLogger(requestLog), operational...))
 	// Se a aplicação cai ele não deixa cair
 	router.Use(middleware.Recoverer)
 	//router.Use(LogRequest)
-	router.Use(middleware.WithValue("jwt", config.TokenAuth))
-	router.Use(middleware.WithValue("jwtExpiresin", config.JwtExpiresIn))
 
 	router.Route("/products", func(r chi.Router) {
-		r.Use(jwtauth.Verifier(&config.TokenAuth))
-		r.Use(jwtauth.Authenticator)
-		r.Post("/", productHandler.CreateProduct)
-		r.Get("/{id}", productHandler.GetProduct)
-		r.Get("/", productHandler.GetProducts)
-		r.Put("/{id}", productHandler.UpdateProduct)
-		r.Delete("/{id}", productHandler.DeleteProduct)
+		r.Use(middlewares.Verifier(tokenKeys))
+		r.Use(middlewares.APIKey(apiKeyService))
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.RejectRevokedTokens(tokenService))
+		r.With(middlewares.RequirePermission(entity.PermissionProductsWrite)).Post("/", productHandler.CreateProduct)
+		r.With(middlewares.RequirePermission(entity.PermissionProductsRead)).Get("/{id}", productHandler.GetProduct)
+		r.With(middlewares.RequirePermission(entity.PermissionProductsRead)).Get("/", productHandler.GetProducts)
+		r.With(middlewares.RequirePermission(entity.PermissionProductsWrite)).Put("/{id}", productHandler.UpdateProduct)
+		r.With(middlewares.RequirePermission(entity.PermissionProductsDelete)).Delete("/{id}", productHandler.DeleteProduct)
+	})
+
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(middlewares.Verifier(tokenKeys))
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.RejectRevokedTokens(tokenService))
+		r.Use(middlewares.RequirePermission(entity.PermissionUsersManage))
+		r.Get("/users", userHandler.GetUsers)
+		r.Put("/users/{id}/role", userHandler.UpdateUserRole)
 	})
 
-	router.Get("/docs/*", httpSwagger.Handler(httpSwagger.URL("http://localhost:8080/docs/doc.json")))
+	router.Get("/healthz", healthHandler.Healthz)
+	router.Get("/readyz", healthHandler.Readyz)
+	router.Get("/version", healthHandler.Version)
+	router.Get("/metrics", appMetrics.Handler().ServeHTTP)
+
+	router.Get("/.well-known/jwks.json", handlers.NewJWKSHandler(tokenKeys).GetJWKS)
+
+	router.Get("/docs/*", httpSwagger.Handler(httpSwagger.URL("/docs/doc.json")))
 
 	//http.HandleFunc("/products", productHandler.CreateProduct)
 
 	router.Post("/users", userHandler.CreateUser)
 	router.Post("/users/generate_token", userHandler.GetJwt)
+	router.Post("/users/refresh", userHandler.Refresh)
+	router.Get("/users/verify", userHandler.VerifyEmail)
+	router.Post("/users/verify/resend", userHandler.ResendVerification)
+	router.Post("/users/password/reset", userHandler.RequestPasswordReset)
+	router.Post("/users/password/reset/confirm", userHandler.ConfirmPasswordReset)
+	router.Get("/users/oidc/{provider}/login", oidcHandler.Login)
+	router.Get("/users/oidc/{provider}/callback", oidcHandler.Callback)
+
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.Verifier(tokenKeys))
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.RejectRevokedTokens(tokenService))
+		r.Post("/users/logout", userHandler.Logout)
+		r.Get("/users/me", userHandler.GetMe)
+		r.Get("/users/me/token", userHandler.GetToken)
+		r.Patch("/users/me", userHandler.UpdateMe)
+		r.Delete("/users/me", userHandler.DeleteMe)
+		r.Post("/users/me/password", userHandler.ChangePassword)
+		r.Post("/users/me/api_keys", userHandler.CreateAPIKey)
+		r.Get("/users/me/api_keys", userHandler.GetAPIKeys)
+		r.Delete("/users/me/api_keys/{id}", userHandler.RevokeAPIKey)
+	})
+
+	srv := server.New(server.Config{
+		Addr:              ":" + config.WebServicePort,
+		ReadTimeout:       time.Second * time.Duration(config.HTTPReadTimeout),
+		ReadHeaderTimeout: time.Second * time.Duration(config.HTTPReadHeaderTimeout),
+		WriteTimeout:      time.Second * time.Duration(config.HTTPWriteTimeout),
+		IdleTimeout:       time.Second * time.Duration(config.HTTPIdleTimeout),
+		MaxHeaderBytes:    config.HTTPMaxHeaderBytes,
+	}, router)
+
+	// SIGINT (Ctrl+C) e SIGTERM (docker stop, kubernetes) encerram sem cortar requisições
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reloader := configs.NewReloader(config, ".", os.Args[1:])
+	reloader.OnReload(func(c *configs.Conf) {
+		tokenService.SetTTL(time.Second*time.Duration(c.JwtExpiresIn), time.Second*time.Duration(c.JwtRefreshExpiresIn))
 
-	http.ListenAndServe(":8080", router)
+		base := time.Second * time.Duration(c.LoginLockoutBase)
+		ceiling := time.Second * time.Duration(c.LoginLockoutMax)
+		loginService.EmailLimits.Configure(c.LoginMaxAttemptsPerEmail, base, ceiling)
+		loginService.IPLimits.Configure(c.LoginMaxAttemptsPerIP, base, ceiling)
+		emailLimits.Email.Configure(c.MailMaxRequestsPerEmail, base, ceiling)
+		emailLimits.IP.Configure(c.MailMaxRequestsPerIP, base, ceiling)
+
+		logLevel.Set(c.SlogLevel())
+		corsOrigins.Set(c.CORSOrigins())
+	})
+
+	// SIGHUP recarrega a configuração sem reiniciar
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	go func() {
+		if err := reloader.Watch(ctx, hup); err != nil {
+			log.Printf("observando a configuração: %v", err)
+		}
+	}()
+
+	err = server.ListenAndRun(ctx, srv, time.Second*time.Duration(config.HTTPShutdownTimeout))
+
+	// Os e-mails pedidos antes do encerramento ainda precisam do banco
+	passwordService.Wait()
+	verificationService.Wait()
+
+	return err
+}
+
+func flushTraces(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := shutdown(ctx); err != nil {
+		log.Printf("enviando os últimos spans: %v", err)
+	}
+}
+
+func closeDatabase(db *gorm.DB) {
+	if err := database.Close(db); err != nil {
+		log.Printf("fechando conexão com o banco: %v", err)
+	}
+}
+
+// purgeRevokedTokens apaga da denylist os tokens que já expiraram
+func purgeRevokedTokens(revokedTokens database.RevokedTokenInterface, interval time.Duration) {
+	for range time.Tick(interval) {
+		if _, err := revokedTokens.DeleteExpired(); err != nil {
+			log.Printf("limpando denylist: %v", err)
+		}
+	}
+}
+
+// purgeLoginAttempts esquece as falhas de login e os pedidos de e-mail que já não bloqueiam ninguém
+func purgeLoginAttempts(interval time.Duration, limiters ...*auth.LoginLimiter) {
+	for range time.Tick(interval) {
+		for _, limiter := range limiters {
+			limiter.Purge()
+		}
+	}
 }
 
 // Request -> middleware -> Handler->respose
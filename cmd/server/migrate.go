@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database/migrations"
+	"gorm.io/gorm"
+)
+
+// runMigrate trata o subcomando "migrate up|down|status"
+func runMigrate(db *gorm.DB, args []string) error {
+	if len(args) != 1 {
+		return errors.New("uso: server migrate up|down|status")
+	}
+
+	migrator := migrations.NewMigrator(db)
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up()
+
+		for _, migration := range done {
+			fmt.Fprintf(os.Stdout, "aplicada %04d_%s\n", migration.Version, migration.Name)
+		}
+
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(os.Stdout, "nenhuma migration pendente")
+		}
+
+		return err
+	case "down":
+		migration, err := migrator.Down()
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stdout, "revertida %04d_%s\n", migration.Version, migration.Name)
+		return nil
+	case "status":
+		status, err := migrator.Status()
+
+		if err != nil {
+			return err
+		}
+
+		for _, s := range status {
+			state := "pendente"
+
+			if s.Applied {
+				state = "aplicada em " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Fprintf(os.Stdout, "%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("subcomando desconhecido %q: use up, down ou status", args[0])
+}
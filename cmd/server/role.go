@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"gorm.io/gorm"
+)
+
+// runRole trata o subcomando "role <email> <papel>", usado para criar o primeiro administrador
+func runRole(db *gorm.DB, args []string) error {
+	if len(args) != 2 {
+		return errors.New("uso: server role <email> admin|editor|viewer")
+	}
+
+	users := database.NewUser(db)
+	user, err := users.FindByEmail(args[0])
+
+	if err != nil {
+		return fmt.Errorf("usuário %s: %w", args[0], err)
+	}
+
+	if err := user.SetRole(args[1]); err != nil {
+		return err
+	}
+
+	if err := users.Update(user); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "%s agora é %s\n", user.Email, user.Role)
+	return nil
+}
@@ -1,45 +1,467 @@
 package configs
 
 import (
-	"github.com/go-chi/jwtauth"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/mail"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/tracing"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
-type conf struct {
-	DBDriver       string `mapstructure:"DB_DRIVER"`
-	DBHost         string `mapstructure:"DB_HOST"`
-	DBPort         string `mapstructure:"DB_PORT"`
-	DBUser         string `mapstructure:"DB_USER"`
-	DBPassword     string `mapstructure:"DB_PASSWORD"`
-	DBName         string `mapstructure:"DB_NAME"`
-	WebServicePort string `mapstructure:"WEB_SERVICE_PORT"`
-	JwtSecret      string `mapstructure:"JWT_SECRET"`
-	JwtExpiresIn   int    `mapstructure:"JWT_EXPIRESIN"`
-	TokenAuth      jwtauth.JWTAuth
+// ErrHelp é devolvido quando -h ou --help foi pedido; o uso já foi impresso
+var ErrHelp = pflag.ErrHelp
+
+type Conf struct {
+	DBDriver                   string             `mapstructure:"DB_DRIVER"`
+	DBHost                     string             `mapstructure:"DB_HOST"`
+	DBPort                     string             `mapstructure:"DB_PORT"`
+	DBUser                     string             `mapstructure:"DB_USER"`
+	DBPassword                 string             `mapstructure:"DB_PASSWORD"`
+	DBName                     string             `mapstructure:"DB_NAME"`
+	DBSSLMode                  string             `mapstructure:"DB_SSLMODE"`
+	DBMaxOpenConns             int                `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns             int                `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime          int                `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	DBAutoMigrate              bool               `mapstructure:"DB_AUTO_MIGRATE"`
+	WebServicePort             string             `mapstructure:"WEB_SERVICE_PORT"`
+	JwtSecret                  string             `mapstructure:"JWT_SECRET"`
+	JwtExpiresIn               int                `mapstructure:"JWT_EXPIRESIN"`
+	JwtRefreshExpiresIn        int                `mapstructure:"JWT_REFRESH_EXPIRESIN"`
+	MailDriver                 string             `mapstructure:"MAIL_DRIVER"`
+	MailDir                    string             `mapstructure:"MAIL_DIR"`
+	MailFrom                   string             `mapstructure:"MAIL_FROM"`
+	PasswordResetURL           string             `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetExpiresIn     int                `mapstructure:"PASSWORD_RESET_EXPIRESIN"`
+	EmailVerificationRequired  bool               `mapstructure:"EMAIL_VERIFICATION_REQUIRED"`
+	EmailVerificationURL       string             `mapstructure:"EMAIL_VERIFICATION_URL"`
+	EmailVerificationExpiresIn int                `mapstructure:"EMAIL_VERIFICATION_EXPIRESIN"`
+	LoginMaxAttemptsPerEmail   int                `mapstructure:"LOGIN_MAX_ATTEMPTS_PER_EMAIL"`
+	LoginMaxAttemptsPerIP      int                `mapstructure:"LOGIN_MAX_ATTEMPTS_PER_IP"`
+	LoginLockoutBase           int                `mapstructure:"LOGIN_LOCKOUT_BASE"`
+	LoginLockoutMax            int                `mapstructure:"LOGIN_LOCKOUT_MAX"`
+	MailMaxRequestsPerEmail    int                `mapstructure:"MAIL_MAX_REQUESTS_PER_EMAIL"`
+	MailMaxRequestsPerIP       int                `mapstructure:"MAIL_MAX_REQUESTS_PER_IP"`
+	PasswordMinLength          int                `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordRequireUpper       bool               `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower       bool               `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit       bool               `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol      bool               `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	PasswordRejectCommon       bool               `mapstructure:"PASSWORD_REJECT_COMMON"`
+	BcryptCost                 int                `mapstructure:"BCRYPT_COST"`
+	JwtKeysDir                 string             `mapstructure:"JWT_KEYS_DIR"`
+	JwtSigningKid              string             `mapstructure:"JWT_SIGNING_KID"`
+	JwtIssuer                  string             `mapstructure:"JWT_ISSUER"`
+	JwtAudience                string             `mapstructure:"JWT_AUDIENCE"`
+	JwtLeeway                  int                `mapstructure:"JWT_LEEWAY"`
+	HTTPReadTimeout            int                `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPReadHeaderTimeout      int                `mapstructure:"HTTP_READ_HEADER_TIMEOUT"`
+	HTTPWriteTimeout           int                `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout            int                `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	HTTPMaxHeaderBytes         int                `mapstructure:"HTTP_MAX_HEADER_BYTES"`
+	HTTPShutdownTimeout        int                `mapstructure:"HTTP_SHUTDOWN_TIMEOUT"`
+	LogLevel                   string             `mapstructure:"LOG_LEVEL"`
+	CORSAllowedOrigins         string             `mapstructure:"CORS_ALLOWED_ORIGINS"`
+	TracingExporter            string             `mapstructure:"TRACING_EXPORTER"`
+	TracingOTLPEndpoint        string             `mapstructure:"TRACING_OTLP_ENDPOINT"`
+	TracingSampleRatio         float64            `mapstructure:"TRACING_SAMPLE_RATIO"`
+	TracingServiceName         string             `mapstructure:"TRACING_SERVICE_NAME"`
+	OIDCProviderNames          string             `mapstructure:"OIDC_PROVIDERS"`
+	OIDCProviders              []OIDCProviderConf `mapstructure:"-"`
+	File                       string             `mapstructure:"-"` // vazio quando não há arquivo
+}
+
+// OIDCProviderConf vem das chaves OIDC_<NOME>_*, uma para cada nome em OIDC_PROVIDERS
+type OIDCProviderConf struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Valores usados quando a chave não aparece em nenhuma fonte. Chaves fora
+// daqui começam vazias, falsas ou zeradas.
+var defaults = map[string]interface{}{
+	"DB_DRIVER":                    database.DriverSqlite,
+	"DB_NAME":                      "teste.db",
+	"DB_MAX_OPEN_CONNS":            10,
+	"DB_MAX_IDLE_CONNS":            5,
+	"DB_CONN_MAX_LIFETIME":         300,
+	"DB_AUTO_MIGRATE":              true,
+	"WEB_SERVICE_PORT":             "8080",
+	"JWT_EXPIRESIN":                300,
+	"JWT_REFRESH_EXPIRESIN":        604800,
+	"JWT_LEEWAY":                   5,
+	"MAIL_DRIVER":                  mail.DriverLog,
+	"MAIL_DIR":                     "mail",
+	"MAIL_FROM":                    "no-reply@localhost",
+	"PASSWORD_RESET_EXPIRESIN":     3600,
+	"EMAIL_VERIFICATION_REQUIRED":  true,
+	"EMAIL_VERIFICATION_URL":       "http://localhost:8080/users/verify",
+	"EMAIL_VERIFICATION_EXPIRESIN": 86400,
+	"LOGIN_MAX_ATTEMPTS_PER_EMAIL": 5,
+	"LOGIN_MAX_ATTEMPTS_PER_IP":    20,
+	"LOGIN_LOCKOUT_BASE":           30,
+	"LOGIN_LOCKOUT_MAX":            900,
+	"MAIL_MAX_REQUESTS_PER_EMAIL":  3,
+	"MAIL_MAX_REQUESTS_PER_IP":     10,
+	"PASSWORD_MIN_LENGTH":          8,
+	"PASSWORD_REQUIRE_LOWER":       true,
+	"PASSWORD_REQUIRE_DIGIT":       true,
+	"PASSWORD_REJECT_COMMON":       true,
+	"BCRYPT_COST":                  12,
+	"HTTP_READ_TIMEOUT":            15,
+	"HTTP_READ_HEADER_TIMEOUT":     5,
+	"HTTP_WRITE_TIMEOUT":           30,
+	"HTTP_IDLE_TIMEOUT":            120,
+	"HTTP_MAX_HEADER_BYTES":        1 << 20,
+	"HTTP_SHUTDOWN_TIMEOUT":        20,
+	"LOG_LEVEL":                    "info",
+	"TRACING_EXPORTER":             tracing.ExporterNone,
+	"TRACING_OTLP_ENDPOINT":        "http://localhost:4318",
+	"TRACING_SAMPLE_RATIO":         1.0,
+	"TRACING_SERVICE_NAME":         "9-api",
+}
+
+// ValidationError reúne todos os problemas encontrados, para corrigi-los de uma vez
+type ValidationError struct {
+	Problems []string
 }
 
-func LoadConfig(path string) (*conf, error) {
-	var cfg conf
+func (e *ValidationError) Error() string {
+	return "configuração inválida:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// LoadConfig junta, da menor para a maior prioridade: os valores padrão, o
+// arquivo de configuração, as variáveis de ambiente e as flags de args. O
+// arquivo é o informado em --config ou CONFIG_FILE (.env, .yaml, .yml, .toml
+// ou .json) e, sem eles, o .env de path, se existir. Qualquer chave pode vir
+// de um arquivo com <CHAVE>_FILE, o que tem prioridade sobre o valor direto.
+// Devolve também os argumentos que sobraram depois das flags.
+func LoadConfig(path string, args []string) (*Conf, []string, error) {
+	v := viper.New()
+	keys := settingKeys()
+	flags := pflag.NewFlagSet("server", pflag.ContinueOnError)
+	flags.String("config", "", "arquivo de configuração (.env, .yaml, .toml ou .json)")
+
+	for _, key := range keys {
+		flags.String(flagName(key), "", "sobrescreve "+key)
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	for _, key := range keys {
+		if value, ok := defaults[key]; ok {
+			v.SetDefault(key, value)
+		}
 
-	viper.SetConfigName("app_config")
-	viper.SetConfigType("env")
-	viper.AddConfigPath(path)
-	viper.SetConfigFile(".env")
-	viper.AutomaticEnv()
+		if err := v.BindEnv(key); err != nil {
+			return nil, nil, err
+		}
 
-	err := viper.ReadInConfig()
+		if err := v.BindEnv(key + "_FILE"); err != nil {
+			return nil, nil, err
+		}
+
+		if err := v.BindPFlag(key, flags.Lookup(flagName(key))); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	file, err := readConfigFile(v, path, flags)
 
 	if err != nil {
-		panic(err)
+		return nil, nil, err
+	}
+
+	if err := readSecretFiles(v, keys); err != nil {
+		return nil, nil, err
+	}
+
+	var cfg Conf
+
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, nil, fmt.Errorf("lendo configuração: %w", err)
 	}
 
-	err = viper.Unmarshal(&cfg)
+	providers, err := loadOIDCProviders(v, cfg.OIDCProviderNames)
 
 	if err != nil {
-		panic(err)
+		return nil, nil, err
+	}
+
+	cfg.OIDCProviders = providers
+	cfg.File = file
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return &cfg, flags.Args(), nil
+}
+
+func readConfigFile(v *viper.Viper, path string, flags *pflag.FlagSet) (string, error) {
+	file, _ := flags.GetString("config")
+
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+
+	if file == "" {
+		file = filepath.Join(path, ".env")
+
+		// O .env é opcional: sem ele valem os padrões e o ambiente
+		if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+	}
+
+	v.SetConfigFile(file)
+
+	// O viper só reconhece o .env pela extensão, não pelo nome
+	if filepath.Base(file) == ".env" {
+		v.SetConfigType("env")
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		return "", fmt.Errorf("lendo %s: %w", file, err)
+	}
+
+	return file, nil
+}
+
+// readSecretFiles troca cada chave que tem <CHAVE>_FILE pelo conteúdo do arquivo
+func readSecretFiles(v *viper.Viper, keys []string) error {
+	for _, key := range keys {
+		value, ok, err := fileValue(v, key)
+
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			v.Set(key, value)
+		}
+	}
+
+	return nil
+}
+
+func fileValue(v *viper.Viper, key string) (string, bool, error) {
+	file := v.GetString(key + "_FILE")
+
+	if file == "" {
+		return "", false, nil
+	}
+
+	data, err := os.ReadFile(file)
+
+	if err != nil {
+		return "", false, fmt.Errorf("lendo %s_FILE: %w", key, err)
+	}
+
+	// Editores e o echo deixam uma quebra de linha no fim do arquivo
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// stringValue lê chaves que não estão em Conf, também aceitando <CHAVE>_FILE
+func stringValue(v *viper.Viper, key string) (string, error) {
+	if err := v.BindEnv(key); err != nil {
+		return "", err
+	}
+
+	if err := v.BindEnv(key + "_FILE"); err != nil {
+		return "", err
+	}
+
+	value, ok, err := fileValue(v, key)
+
+	if ok || err != nil {
+		return value, err
 	}
 
-	cfg.TokenAuth = *jwtauth.New("HS256", []byte(cfg.JwtSecret), nil)
+	return v.GetString(key), nil
+}
+
+func loadOIDCProviders(v *viper.Viper, names string) ([]OIDCProviderConf, error) {
+	var providers []OIDCProviderConf
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		values := map[string]string{}
+
+		for _, suffix := range []string{"ISSUER", "CLIENT_ID", "CLIENT_SECRET", "REDIRECT_URL", "SCOPES"} {
+			value, err := stringValue(v, prefix+suffix)
+
+			if err != nil {
+				return nil, err
+			}
+
+			values[suffix] = value
+		}
+
+		providers = append(providers, OIDCProviderConf{
+			Name:         name,
+			Issuer:       values["ISSUER"],
+			ClientID:     values["CLIENT_ID"],
+			ClientSecret: values["CLIENT_SECRET"],
+			RedirectURL:  values["REDIRECT_URL"],
+			Scopes:       strings.Fields(values["SCOPES"]),
+		})
+	}
+
+	return providers, nil
+}
+
+// Validate confere os valores obrigatórios e os limites de cada chave
+func (c *Conf) Validate() error {
+	var problems []string
+
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	_, err := database.NormalizeDriver(c.DBDriver)
+	check(err == nil, "DB_DRIVER: %v", err)
+
+	port, err := strconv.Atoi(c.WebServicePort)
+	check(err == nil && port > 0 && port <= 65535, "WEB_SERVICE_PORT: porta inválida %q", c.WebServicePort)
+
+	// Também assina os links de verificação e o cookie do login OIDC
+	check(c.JwtSecret != "", "JWT_SECRET: obrigatório")
+	check(c.JwtKeysDir == "" || c.JwtSigningKid != "", "JWT_SIGNING_KID: obrigatório com JWT_KEYS_DIR")
+
+	_, err = mail.New(c.MailDriver, c.MailDir)
+	check(err == nil, "MAIL_DRIVER: %v", err)
+
+	for key, value := range map[string]int{
+		"JWT_EXPIRESIN":                c.JwtExpiresIn,
+		"JWT_REFRESH_EXPIRESIN":        c.JwtRefreshExpiresIn,
+		"PASSWORD_RESET_EXPIRESIN":     c.PasswordResetExpiresIn,
+		"EMAIL_VERIFICATION_EXPIRESIN": c.EmailVerificationExpiresIn,
+		"LOGIN_MAX_ATTEMPTS_PER_EMAIL": c.LoginMaxAttemptsPerEmail,
+		"LOGIN_MAX_ATTEMPTS_PER_IP":    c.LoginMaxAttemptsPerIP,
+		"MAIL_MAX_REQUESTS_PER_EMAIL":  c.MailMaxRequestsPerEmail,
+		"MAIL_MAX_REQUESTS_PER_IP":     c.MailMaxRequestsPerIP,
+		"LOGIN_LOCKOUT_BASE":           c.LoginLockoutBase,
+		"HTTP_SHUTDOWN_TIMEOUT":        c.HTTPShutdownTimeout,
+	} {
+		check(value > 0, "%s: deve ser maior que zero", key)
+	}
+
+	for key, value := range map[string]int{
+		"DB_MAX_OPEN_CONNS":        c.DBMaxOpenConns,
+		"DB_MAX_IDLE_CONNS":        c.DBMaxIdleConns,
+		"DB_CONN_MAX_LIFETIME":     c.DBConnMaxLifetime,
+		"JWT_LEEWAY":               c.JwtLeeway,
+		"HTTP_READ_TIMEOUT":        c.HTTPReadTimeout,
+		"HTTP_READ_HEADER_TIMEOUT": c.HTTPReadHeaderTimeout,
+		"HTTP_WRITE_TIMEOUT":       c.HTTPWriteTimeout,
+		"HTTP_IDLE_TIMEOUT":        c.HTTPIdleTimeout,
+		"HTTP_MAX_HEADER_BYTES":    c.HTTPMaxHeaderBytes,
+	} {
+		check(value >= 0, "%s: não pode ser negativo", key)
+	}
+
+	check(c.LoginLockoutMax >= c.LoginLockoutBase, "LOGIN_LOCKOUT_MAX: deve ser maior ou igual a LOGIN_LOCKOUT_BASE")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.LogLevel)) == nil, "LOG_LEVEL: nível inválido %q, use debug, info, warn ou error", c.LogLevel)
+
+	for _, origin := range c.CORSOrigins() {
+		check(origin == "*" || isOrigin(origin), "CORS_ALLOWED_ORIGINS: origem inválida %q, use esquema e host, como https://app.example.com", origin)
+	}
+
+	err = tracing.ValidateExporter(c.TracingExporter)
+	check(err == nil, "TRACING_EXPORTER: %v", err)
+	check(c.TracingExporter != tracing.ExporterOTLP || isAbsoluteURL(c.TracingOTLPEndpoint), "TRACING_OTLP_ENDPOINT: URL obrigatória com o exporter otlp")
+	check(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1, "TRACING_SAMPLE_RATIO: deve estar entre 0 e 1")
+
+	for _, provider := range c.OIDCProviders {
+		prefix := "OIDC_" + strings.ToUpper(provider.Name) + "_"
+		check(isAbsoluteURL(provider.Issuer), "%sISSUER: URL obrigatória", prefix)
+		check(provider.ClientID != "", "%sCLIENT_ID: obrigatório", prefix)
+		check(isAbsoluteURL(provider.RedirectURL), "%sREDIRECT_URL: URL obrigatória", prefix)
+	}
+
+	if len(problems) > 0 {
+		// A ordem dos mapas varia; o relatório não
+		sort.Strings(problems)
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// SlogLevel converte LOG_LEVEL, já conferido por Validate
+func (c *Conf) SlogLevel() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(c.LogLevel))
+
+	return level
+}
+
+// CORSOrigins separa as origens de CORS_ALLOWED_ORIGINS; vazio desliga o CORS
+func (c *Conf) CORSOrigins() []string {
+	var origins []string
+
+	for _, origin := range strings.Split(c.CORSAllowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return origins
+}
+
+// isOrigin aceita só esquema e host, que é o que o navegador manda em Origin
+func isOrigin(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "" && parsed.Path == "" && parsed.RawQuery == ""
+}
+
+func isAbsoluteURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && parsed.Scheme != "" && parsed.Host != ""
+}
+
+// settingKeys lista as chaves das tags mapstructure de Conf
+func settingKeys() []string {
+	var keys []string
+	t := reflect.TypeOf(Conf{})
+
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("mapstructure"); key != "" && key != "-" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
 
-	return &cfg, nil
+// flagName converte DB_DRIVER em --db-driver
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
 }
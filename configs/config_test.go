@@ -0,0 +1,180 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	file := filepath.Join(dir, name)
+
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	t.Setenv("JWT_SECRET", "segredo")
+
+	// Sem .env valem os padrões e o ambiente
+	cfg, args, err := LoadConfig(t.TempDir(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, args)
+	assert.Equal(t, "segredo", cfg.JwtSecret)
+	assert.Equal(t, "8080", cfg.WebServicePort)
+	assert.Equal(t, "sqlite", cfg.DBDriver)
+	assert.Equal(t, 300, cfg.JwtExpiresIn)
+	assert.True(t, cfg.DBAutoMigrate)
+	assert.False(t, cfg.PasswordRequireUpper)
+	assert.Equal(t, 1<<20, cfg.HTTPMaxHeaderBytes)
+}
+
+func TestLoadConfigReportsEveryProblem(t *testing.T) {
+	t.Setenv("WEB_SERVICE_PORT", "porta")
+	t.Setenv("JWT_EXPIRESIN", "0")
+	t.Setenv("DB_DRIVER", "oracle")
+	t.Setenv("JWT_KEYS_DIR", "keys")
+
+	_, _, err := LoadConfig(t.TempDir(), nil)
+
+	var invalid *ValidationError
+	assert.ErrorAs(t, err, &invalid)
+	assert.Len(t, invalid.Problems, 5)
+	assert.Contains(t, err.Error(), "JWT_SECRET: obrigatório")
+	assert.Contains(t, err.Error(), "JWT_SIGNING_KID: obrigatório com JWT_KEYS_DIR")
+	assert.Contains(t, err.Error(), `WEB_SERVICE_PORT: porta inválida "porta"`)
+	assert.Contains(t, err.Error(), "JWT_EXPIRESIN: deve ser maior que zero")
+	assert.Contains(t, err.Error(), "DB_DRIVER")
+}
+
+func TestLoadConfigRejectsMalformedValues(t *testing.T) {
+	t.Setenv("JWT_SECRET", "segredo")
+	t.Setenv("JWT_EXPIRESIN", "cinco minutos")
+
+	_, _, err := LoadConfig(t.TempDir(), nil)
+	assert.ErrorContains(t, err, "JWT_EXPIRESIN")
+}
+
+func TestLoadConfigTracing(t *testing.T) {
+	t.Setenv("JWT_SECRET", "segredo")
+
+	cfg, _, err := LoadConfig(t.TempDir(), []string{"--tracing-exporter", "otlp", "--tracing-sample-ratio", "0.25"})
+	assert.NoError(t, err)
+	assert.Equal(t, "otlp", cfg.TracingExporter)
+	assert.Equal(t, 0.25, cfg.TracingSampleRatio)
+	assert.Equal(t, "http://localhost:4318", cfg.TracingOTLPEndpoint)
+
+	_, _, err = LoadConfig(t.TempDir(), []string{"--tracing-exporter", "zipkin", "--tracing-sample-ratio", "2"})
+
+	var invalid *ValidationError
+	assert.ErrorAs(t, err, &invalid)
+	assert.Len(t, invalid.Problems, 2)
+	assert.Contains(t, err.Error(), "TRACING_EXPORTER")
+	assert.Contains(t, err.Error(), "TRACING_SAMPLE_RATIO: deve estar entre 0 e 1")
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "config.yaml", "JWT_SECRET: do-arquivo\nWEB_SERVICE_PORT: 7000\nDB_NAME: arquivo.db\nMAIL_FROM: arquivo@localhost\n")
+	t.Setenv("DB_NAME", "ambiente.db")
+	t.Setenv("MAIL_FROM", "ambiente@localhost")
+
+	cfg, args, err := LoadConfig(dir, []string{"--config", file, "--mail-from=flag@localhost", "migrate", "up"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"migrate", "up"}, args)
+	assert.Equal(t, "do-arquivo", cfg.JwtSecret)
+	assert.Equal(t, "7000", cfg.WebServicePort)
+	assert.Equal(t, "ambiente.db", cfg.DBName)
+	assert.Equal(t, "flag@localhost", cfg.MailFrom)
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "JWT_SECRET=do-env\nJWT_LEEWAY=7\n")
+
+	cfg, _, err := LoadConfig(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "do-env", cfg.JwtSecret)
+	assert.Equal(t, 7, cfg.JwtLeeway)
+
+	t.Setenv("CONFIG_FILE", writeFile(t, dir, "config.toml", "JWT_SECRET = \"do-toml\"\nEMAIL_VERIFICATION_REQUIRED = false\n"))
+
+	cfg, _, err = LoadConfig(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "do-toml", cfg.JwtSecret)
+	assert.False(t, cfg.EmailVerificationRequired)
+
+	// Um arquivo pedido explicitamente precisa existir
+	_, _, err = LoadConfig(dir, []string{"--config", filepath.Join(dir, "nao-existe.yaml")})
+	assert.Error(t, err)
+}
+
+func TestLoadConfigSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("JWT_SECRET", "do-ambiente")
+	t.Setenv("JWT_SECRET_FILE", writeFile(t, dir, "jwt_secret", "do-arquivo\n"))
+	t.Setenv("DB_PASSWORD_FILE", writeFile(t, dir, "db_password", "s3nha"))
+
+	cfg, _, err := LoadConfig(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "do-arquivo", cfg.JwtSecret)
+	assert.Equal(t, "s3nha", cfg.DBPassword)
+
+	t.Setenv("DB_PASSWORD_FILE", filepath.Join(dir, "nao-existe"))
+	_, _, err = LoadConfig(dir, nil)
+	assert.ErrorContains(t, err, "DB_PASSWORD_FILE")
+}
+
+func TestLoadConfigOIDCProviders(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("JWT_SECRET", "segredo")
+	t.Setenv("OIDC_PROVIDERS", "Google, keycloak")
+	t.Setenv("OIDC_GOOGLE_ISSUER", "https://accounts.google.com")
+	t.Setenv("OIDC_GOOGLE_CLIENT_ID", "api")
+	t.Setenv("OIDC_GOOGLE_CLIENT_SECRET_FILE", writeFile(t, dir, "google", "segredo-google\n"))
+	t.Setenv("OIDC_GOOGLE_REDIRECT_URL", "http://localhost:8080/users/oidc/google/callback")
+	t.Setenv("OIDC_GOOGLE_SCOPES", "openid email")
+
+	_, _, err := LoadConfig(dir, nil)
+
+	var invalid *ValidationError
+	assert.ErrorAs(t, err, &invalid)
+	assert.Equal(t, []string{
+		"OIDC_KEYCLOAK_CLIENT_ID: obrigatório",
+		"OIDC_KEYCLOAK_ISSUER: URL obrigatória",
+		"OIDC_KEYCLOAK_REDIRECT_URL: URL obrigatória",
+	}, invalid.Problems)
+
+	t.Setenv("OIDC_PROVIDERS", "google")
+	cfg, _, err := LoadConfig(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []OIDCProviderConf{{
+		Name:         "google",
+		Issuer:       "https://accounts.google.com",
+		ClientID:     "api",
+		ClientSecret: "segredo-google",
+		RedirectURL:  "http://localhost:8080/users/oidc/google/callback",
+		Scopes:       []string{"openid", "email"},
+	}}, cfg.OIDCProviders)
+}
+
+func TestLoadConfigFlags(t *testing.T) {
+	t.Setenv("JWT_SECRET", "segredo")
+
+	cfg, _, err := LoadConfig(t.TempDir(), []string{"--web-service-port", "9000", "--db-auto-migrate=false"})
+	assert.NoError(t, err)
+	assert.Equal(t, "9000", cfg.WebServicePort)
+	assert.False(t, cfg.DBAutoMigrate)
+
+	_, _, err = LoadConfig(t.TempDir(), []string{"--nao-existe"})
+	assert.Error(t, err)
+
+	_, _, err = LoadConfig(t.TempDir(), []string{"--help"})
+	assert.ErrorIs(t, err, ErrHelp)
+}
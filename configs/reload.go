@@ -0,0 +1,196 @@
+package configs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Chaves que podem mudar com o servidor no ar. As demais são lidas só na
+// inicialização: uma mudança nelas é registrada no log e fica para o próximo
+// restart.
+var reloadableKeys = map[string]bool{
+	"JWT_EXPIRESIN":                true,
+	"JWT_REFRESH_EXPIRESIN":        true,
+	"LOGIN_MAX_ATTEMPTS_PER_EMAIL": true,
+	"LOGIN_MAX_ATTEMPTS_PER_IP":    true,
+	"LOGIN_LOCKOUT_BASE":           true,
+	"LOGIN_LOCKOUT_MAX":            true,
+	"MAIL_MAX_REQUESTS_PER_EMAIL":  true,
+	"MAIL_MAX_REQUESTS_PER_IP":     true,
+	"LOG_LEVEL":                    true,
+	"CORS_ALLOWED_ORIGINS":         true,
+}
+
+// Editores costumam gravar o arquivo em vários passos; espera assentar
+const reloadDebounce = 200 * time.Millisecond
+
+// Reloader guarda a configuração em uso e a troca quando o arquivo muda ou
+// quando Watch recebe um sinal, avisando quem se registrou em OnReload.
+type Reloader struct {
+	path string
+	args []string
+
+	current   atomic.Pointer[Conf]
+	mu        sync.Mutex
+	listeners []func(*Conf)
+}
+
+// NewReloader parte de cfg e recarrega com os mesmos path e args do LoadConfig
+func NewReloader(cfg *Conf, path string, args []string) *Reloader {
+	r := &Reloader{path: path, args: args}
+	r.current.Store(cfg)
+
+	return r
+}
+
+func (r *Reloader) Current() *Conf {
+	return r.current.Load()
+}
+
+// OnReload registra fn para ser chamada com a nova configuração a cada recarga
+func (r *Reloader) OnReload(fn func(*Conf)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.listeners = append(r.listeners, fn)
+}
+
+// Reload lê todas as fontes de novo. Uma configuração inválida é recusada e a
+// atual continua valendo. Das chaves que mudaram, só as de reloadableKeys são
+// aplicadas; devolve quais foram.
+func (r *Reloader) Reload() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, _, err := LoadConfig(r.path, r.args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	current := r.Current()
+	merged := *current
+	var applied, pending []string
+
+	for _, change := range diff(current, next) {
+		if !reloadableKeys[change.key] {
+			pending = append(pending, change.key)
+			continue
+		}
+
+		reflect.ValueOf(&merged).Elem().FieldByName(change.field).Set(change.value)
+		applied = append(applied, fmt.Sprintf("%s: %v -> %v", change.key, change.old, change.value))
+	}
+
+	if len(pending) > 0 {
+		log.Printf("configuração: %s mudou e só vale após reiniciar", strings.Join(pending, ", "))
+	}
+
+	if len(applied) == 0 {
+		return nil, nil
+	}
+
+	if err := merged.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.current.Store(&merged)
+	log.Printf("configuração recarregada: %s", strings.Join(applied, ", "))
+
+	for _, fn := range r.listeners {
+		fn(&merged)
+	}
+
+	return applied, nil
+}
+
+// Watch recarrega quando o arquivo de configuração muda ou quando chega algo
+// em signals (o SIGHUP, no main), até ctx ser cancelado. Erros de recarga vão
+// para o log e não interrompem a observação.
+func (r *Reloader) Watch(ctx context.Context, signals <-chan os.Signal) error {
+	var events chan fsnotify.Event
+	file := r.Current().File
+
+	if file != "" {
+		watcher, err := fsnotify.NewWatcher()
+
+		if err != nil {
+			return err
+		}
+
+		defer watcher.Close()
+
+		// Observa o diretório: quem salva trocando o arquivo (vim, kubernetes) gera um novo inode
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			return err
+		}
+
+		events = watcher.Events
+	}
+
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			if filepath.Clean(event.Name) == filepath.Clean(file) && !event.Has(fsnotify.Chmod) {
+				debounce = time.After(reloadDebounce)
+			}
+
+			continue
+		case <-debounce:
+			debounce = nil
+		case <-signals:
+		}
+
+		if _, err := r.Reload(); err != nil {
+			log.Printf("recarga da configuração recusada, mantendo a atual: %v", err)
+		}
+	}
+}
+
+type change struct {
+	key   string
+	field string
+	old   interface{}
+	value reflect.Value
+}
+
+// diff compara as chaves de Conf; OIDCProviders entra como OIDC_<NOME>_*
+func diff(current, next *Conf) []change {
+	var changes []change
+	t := reflect.TypeOf(*current)
+	before := reflect.ValueOf(*current)
+	after := reflect.ValueOf(*next)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+
+		if field.Name == "File" {
+			continue
+		}
+
+		if key == "-" {
+			key = "OIDC_<NOME>_*"
+		}
+
+		if !reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			changes = append(changes, change{key: key, field: field.Name, old: before.Field(i).Interface(), value: after.Field(i)})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,142 @@
+package configs
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestReloader(t *testing.T, content string) (*Reloader, string) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "config.yaml", content)
+	args := []string{"--config", file}
+
+	cfg, _, err := LoadConfig(dir, args)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewReloader(cfg, dir, args), file
+}
+
+func TestReloadAppliesOnlyReloadableKeys(t *testing.T) {
+	reloader, file := newTestReloader(t, "JWT_SECRET: segredo\nJWT_EXPIRESIN: 300\nWEB_SERVICE_PORT: 8080\n")
+
+	var received *Conf
+	reloader.OnReload(func(cfg *Conf) { received = cfg })
+
+	os.WriteFile(file, []byte("JWT_SECRET: outro\nJWT_EXPIRESIN: 600\nWEB_SERVICE_PORT: 9090\n"), 0o600)
+
+	applied, err := reloader.Reload()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"JWT_EXPIRESIN: 300 -> 600"}, applied)
+
+	current := reloader.Current()
+	assert.Same(t, current, received)
+	assert.Equal(t, 600, current.JwtExpiresIn)
+
+	// Porta e segredo só mudam reiniciando
+	assert.Equal(t, "8080", current.WebServicePort)
+	assert.Equal(t, "segredo", current.JwtSecret)
+}
+
+func TestReloadRejectsInvalidConfig(t *testing.T) {
+	reloader, file := newTestReloader(t, "JWT_SECRET: segredo\nLOGIN_LOCKOUT_BASE: 30\n")
+	before := reloader.Current()
+	called := false
+	reloader.OnReload(func(*Conf) { called = true })
+
+	os.WriteFile(file, []byte("JWT_SECRET: segredo\nLOGIN_LOCKOUT_BASE: 3000\n"), 0o600)
+	_, err := reloader.Reload()
+	assert.ErrorContains(t, err, "LOGIN_LOCKOUT_MAX")
+
+	os.WriteFile(file, []byte("JWT_SECRET: [segredo\n"), 0o600)
+	_, err = reloader.Reload()
+	assert.Error(t, err)
+
+	assert.Same(t, before, reloader.Current())
+	assert.False(t, called)
+}
+
+func TestReloadWithoutChanges(t *testing.T) {
+	reloader, _ := newTestReloader(t, "JWT_SECRET: segredo\n")
+	before := reloader.Current()
+
+	applied, err := reloader.Reload()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.Same(t, before, reloader.Current())
+}
+
+func TestWatchReloadsOnFileChangeAndSignal(t *testing.T) {
+	reloader, file := newTestReloader(t, "JWT_SECRET: segredo\nJWT_EXPIRESIN: 300\n")
+	reloaded := make(chan int, 4)
+	reloader.OnReload(func(cfg *Conf) { reloaded <- cfg.JwtExpiresIn })
+
+	signals := make(chan os.Signal, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- reloader.Watch(ctx, signals)
+	}()
+
+	// Dá tempo do watcher começar a observar o diretório
+	time.Sleep(50 * time.Millisecond)
+	os.WriteFile(file, []byte("JWT_SECRET: segredo\nJWT_EXPIRESIN: 600\n"), 0o600)
+
+	select {
+	case ttl := <-reloaded:
+		assert.Equal(t, 600, ttl)
+	case <-time.After(5 * time.Second):
+		t.Fatal("a mudança no arquivo não recarregou a configuração")
+	}
+
+	// Com o SIGHUP a recarga é imediata, inclusive de mudanças de ambiente
+	t.Setenv("JWT_EXPIRESIN", "900")
+	signals <- os.Interrupt
+
+	select {
+	case ttl := <-reloaded:
+		assert.Equal(t, 900, ttl)
+	case <-time.After(5 * time.Second):
+		t.Fatal("o sinal não recarregou a configuração")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestReloadLogLevelAndCORSOrigins(t *testing.T) {
+	reloader, file := newTestReloader(t, "JWT_SECRET: segredo\n")
+	assert.Equal(t, slog.LevelInfo, reloader.Current().SlogLevel())
+	assert.Empty(t, reloader.Current().CORSOrigins())
+
+	var received *Conf
+	reloader.OnReload(func(cfg *Conf) { received = cfg })
+
+	os.WriteFile(file, []byte("JWT_SECRET: segredo\nLOG_LEVEL: warn\nCORS_ALLOWED_ORIGINS: https://app.example.com, http://localhost:3000\n"), 0o600)
+
+	applied, err := reloader.Reload()
+	assert.NoError(t, err)
+	assert.Len(t, applied, 2)
+	assert.Equal(t, slog.LevelWarn, received.SlogLevel())
+	assert.Equal(t, []string{"https://app.example.com", "http://localhost:3000"}, received.CORSOrigins())
+}
+
+func TestReloadRejectsInvalidLogLevelAndCORSOrigins(t *testing.T) {
+	reloader, file := newTestReloader(t, "JWT_SECRET: segredo\n")
+	before := reloader.Current()
+
+	os.WriteFile(file, []byte("JWT_SECRET: segredo\nLOG_LEVEL: verbose\nCORS_ALLOWED_ORIGINS: https://app.example.com/login\n"), 0o600)
+
+	_, err := reloader.Reload()
+	assert.ErrorContains(t, err, `LOG_LEVEL: nível inválido "verbose"`)
+	assert.ErrorContains(t, err, `CORS_ALLOWED_ORIGINS: origem inválida "https://app.example.com/login"`)
+	assert.Same(t, before, reloader.Current())
+}
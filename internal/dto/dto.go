@@ -1,21 +1,129 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+)
+
 type CreateProductInput struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	Name  string  `json:"name" validate:"required,notblank,max=255"`
+	Price float64 `json:"price" validate:"gt=0"`
 }
 
 type CreateUserInput struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Name     string `json:"name" validate:"required,notblank,max=255"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,max=72"`
 }
 
 type GetJWTInput struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,max=255"`
+	Password string `json:"password" validate:"required,max=72"`
 }
 
 type GetJWTOutput struct {
-	AcessToken string `json:"acess_token"`
+	AcessToken   string `json:"acess_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in" example:"300"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=128"`
+}
+
+// LogoutInput é opcional: sem refresh token apenas o access token é revogado
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token" validate:"max=128"`
+}
+
+// ProductListOutput é o envelope da listagem de produtos
+type ProductListOutput struct {
+	Items      []entity.Product `json:"items"`
+	Page       int              `json:"page,omitempty"`
+	Limit      int              `json:"limit"`
+	Total      int64            `json:"total"`
+	TotalPages int              `json:"total_pages,omitempty"`
+	Next       string           `json:"next,omitempty"`
+	Prev       string           `json:"prev,omitempty"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+type UpdateRoleInput struct {
+	Role string `json:"role" validate:"required,oneof=admin editor viewer"`
+}
+
+// UpdateUserInput altera apenas os campos enviados
+type UpdateUserInput struct {
+	Name  *string `json:"name" validate:"omitnil,notblank,max=255"`
+	Email *string `json:"email" validate:"omitnil,email,max=255"`
+}
+
+// UserListOutput é o envelope da listagem de usuários
+type UserListOutput struct {
+	Items      []entity.User `json:"items"`
+	Page       int           `json:"page"`
+	Limit      int           `json:"limit"`
+	Total      int64         `json:"total"`
+	TotalPages int           `json:"total_pages"`
+	Next       string        `json:"next,omitempty"`
+	Prev       string        `json:"prev,omitempty"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=72"`
+	NewPassword     string `json:"new_password" validate:"required,max=72"`
+}
+
+type PasswordResetRequestInput struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type PasswordResetConfirmInput struct {
+	Token    string `json:"token" validate:"required,max=128"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+type ResendVerificationInput struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type CreateAPIKeyInput struct {
+	Name string `json:"name" validate:"required,notblank,max=255" example:"importador noturno"`
+}
+
+// CreateAPIKeyOutput traz a única cópia da chave em texto puro
+type CreateAPIKeyOutput struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	Prefix    string    `json:"prefix" example:"ak_Jx2m9QzL"`
+	Key       string    `json:"key"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenClaimsOutput descreve o access token usado na requisição
+type TokenClaimsOutput struct {
+	Subject   string    `json:"sub"`
+	Role      string    `json:"role" example:"viewer"`
+	Issuer    string    `json:"iss,omitempty" example:"https://api.example.com"`
+	Audience  []string  `json:"aud,omitempty"`
+	JwtId     string    `json:"jti"`
+	IssuedAt  time.Time `json:"iat"`
+	NotBefore time.Time `json:"nbf"`
+	ExpiresAt time.Time `json:"exp"`
+	ExpiresIn int64     `json:"expires_in" example:"240"`
+}
+
+// HealthOutput traz o resultado de cada verificação do /readyz
+type HealthOutput struct {
+	Status string            `json:"status" example:"ok"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type VersionOutput struct {
+	Version   string `json:"version" example:"v1.4.0"`
+	Commit    string `json:"commit" example:"3f408a5"`
+	BuildTime string `json:"build_time" example:"2024-01-02T15:04:05Z"`
+	GoVersion string `json:"go_version" example:"go1.21.4"`
 }
@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+)
+
+// Prefixo que identifica as API keys em logs e em varreduras de segredos
+const apiKeyPrefix = "ak_"
+
+// Quantos caracteres da chave ficam visíveis na listagem
+const apiKeyVisibleLength = len(apiKeyPrefix) + 8
+
+var ErrAPIKeyNameIsRequired = errors.New("Nome da API key obrigatório")
+
+// APIKey deixa jobs acessarem a API em nome do usuário sem guardar a senha.
+// Como nos tokens opacos, só o hash é gravado; o Prefix ajuda a reconhecer a chave.
+type APIKey struct {
+	Id         entity.Id  `json:"id"`
+	UserId     entity.Id  `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// NewAPIKey devolve o registro e a chave em texto puro, que só existe neste momento
+func NewAPIKey(userId entity.Id, name string) (*APIKey, string, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return nil, "", ErrAPIKeyNameIsRequired
+	}
+
+	random, err := randomToken()
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	plain := apiKeyPrefix + random
+
+	return &APIKey{
+		Id:        entity.NewId(),
+		UserId:    userId,
+		Name:      name,
+		Prefix:    plain[:apiKeyVisibleLength],
+		KeyHash:   HashToken(plain),
+		CreatedAt: time.Now(),
+	}, plain, nil
+}
+
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	userId := entity.NewId()
+	key, plain, err := NewAPIKey(userId, "importador")
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, "ak_"))
+	assert.True(t, strings.HasPrefix(plain, key.Prefix))
+	assert.Len(t, key.Prefix, 11)
+	assert.Equal(t, HashToken(plain), key.KeyHash)
+	assert.NotEqual(t, plain, key.KeyHash)
+	assert.Equal(t, userId, key.UserId)
+	assert.False(t, key.IsRevoked())
+
+	_, other, _ := NewAPIKey(userId, "importador")
+	assert.NotEqual(t, plain, other)
+
+	_, _, err = NewAPIKey(userId, "")
+	assert.ErrorIs(t, err, ErrAPIKeyNameIsRequired)
+}
@@ -0,0 +1,177 @@
+package entity
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrPasswordIsRequired   = errors.New("Senha obrigatória")
+	ErrPasswordTooShort     = errors.New("Senha curta demais")
+	ErrPasswordTooLong      = errors.New("Senha longa demais")
+	ErrPasswordMissingClass = errors.New("Senha sem os tipos de caractere exigidos")
+	ErrPasswordTooCommon    = errors.New("Senha muito comum")
+	ErrInvalidPasswordCost  = errors.New("Custo do bcrypt inválido")
+)
+
+// O bcrypt ignora o que passa de 72 bytes
+const maxPasswordBytes = 72
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+var commonPasswords = parseCommonPasswords(commonPasswordsFile)
+
+// PasswordPolicy descreve as regras para novas senhas
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// RejectCommon recusa as senhas da lista em common_passwords.txt
+	RejectCommon bool
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:    8,
+	RequireLower: true,
+	RequireDigit: true,
+	RejectCommon: true,
+}
+
+var passwordSettings = struct {
+	sync.RWMutex
+	policy PasswordPolicy
+	cost   int
+}{
+	policy: DefaultPasswordPolicy,
+	cost:   bcrypt.DefaultCost,
+}
+
+// ConfigurePasswords define a política e o custo do bcrypt usados por NewUser e ChangePassword
+func ConfigurePasswords(policy PasswordPolicy, cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("%w: %d (use de %d a %d)", ErrInvalidPasswordCost, cost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	passwordSettings.Lock()
+	defer passwordSettings.Unlock()
+
+	passwordSettings.policy = policy
+	passwordSettings.cost = cost
+
+	return nil
+}
+
+// PasswordCost é o custo do bcrypt usado nos hashes novos
+func PasswordCost() int {
+	_, cost := currentPasswordSettings()
+	return cost
+}
+
+func currentPasswordSettings() (PasswordPolicy, int) {
+	passwordSettings.RLock()
+	defer passwordSettings.RUnlock()
+
+	return passwordSettings.policy, passwordSettings.cost
+}
+
+// Validate devolve a primeira regra que a senha não cumpre
+func (p PasswordPolicy) Validate(password string) error {
+	if password == "" {
+		return ErrPasswordIsRequired
+	}
+
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("%w: no máximo %d bytes", ErrPasswordTooLong, maxPasswordBytes)
+	}
+
+	if length := len([]rune(password)); length < p.MinLength {
+		return fmt.Errorf("%w: no mínimo %d caracteres", ErrPasswordTooShort, p.MinLength)
+	}
+
+	if missing := p.missingClasses(password); len(missing) > 0 {
+		return fmt.Errorf("%w: inclua %s", ErrPasswordMissingClass, strings.Join(missing, ", "))
+	}
+
+	if p.RejectCommon {
+		if _, ok := commonPasswords[strings.ToLower(password)]; ok {
+			return ErrPasswordTooCommon
+		}
+	}
+
+	return nil
+}
+
+func (p PasswordPolicy) missingClasses(password string) []string {
+	var upper, lower, digit, symbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	var missing []string
+
+	if p.RequireUpper && !upper {
+		missing = append(missing, "letra maiúscula")
+	}
+
+	if p.RequireLower && !lower {
+		missing = append(missing, "letra minúscula")
+	}
+
+	if p.RequireDigit && !digit {
+		missing = append(missing, "número")
+	}
+
+	if p.RequireSymbol && !symbol {
+		missing = append(missing, "símbolo")
+	}
+
+	return missing
+}
+
+// hashPassword valida a senha com a política atual e gera o hash com o custo configurado
+func hashPassword(password string) (string, error) {
+	policy, cost := currentPasswordSettings()
+
+	if err := policy.Validate(password); err != nil {
+		return "", err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func parseCommonPasswords(content string) map[string]struct{} {
+	passwords := map[string]struct{}{}
+
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.ToLower(strings.TrimSpace(line)); line != "" {
+			passwords[line] = struct{}{}
+		}
+	}
+
+	return passwords
+}
@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+)
+
+// PasswordResetToken autoriza uma única troca de senha sem a senha atual.
+// Assim como no refresh token, só o hash do valor enviado por e-mail é gravado.
+type PasswordResetToken struct {
+	Id        entity.Id  `json:"id"`
+	UserId    entity.Id  `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+func NewPasswordResetToken(userId entity.Id, ttl time.Duration) (*PasswordResetToken, string, error) {
+	plain, err := randomToken()
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+
+	return &PasswordResetToken{
+		Id:        entity.NewId(),
+		UserId:    userId,
+		TokenHash: HashToken(plain),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, plain, nil
+}
+
+func (t *PasswordResetToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *PasswordResetToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPasswordResetToken(t *testing.T) {
+	userId := entity.NewId()
+	token, plain, err := NewPasswordResetToken(userId, time.Hour)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, plain)
+	assert.Equal(t, userId, token.UserId)
+	assert.Equal(t, HashToken(plain), token.TokenHash)
+	assert.False(t, token.IsExpired())
+	assert.False(t, token.IsUsed())
+
+	expired, _, err := NewPasswordResetToken(userId, -time.Second)
+	assert.Nil(t, err)
+	assert.True(t, expired.IsExpired())
+}
+
+func TestUser_ChangePassword(t *testing.T) {
+	user, _ := NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	old := user.Password
+
+	assert.Nil(t, user.ChangePassword("novasenha1"))
+	assert.NotEqual(t, old, user.Password)
+	assert.True(t, user.ValidatePassword("novasenha1"))
+	assert.False(t, user.ValidatePassword("rafa2024x"))
+}
@@ -0,0 +1,88 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func configurePasswords(t *testing.T, policy PasswordPolicy, cost int) {
+	t.Helper()
+	assert.NoError(t, ConfigurePasswords(policy, cost))
+	t.Cleanup(func() { ConfigurePasswords(DefaultPasswordPolicy, bcrypt.DefaultCost) })
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		RejectCommon:  true,
+	}
+
+	assert.NoError(t, policy.Validate("Rafa-2024xy"))
+	assert.ErrorIs(t, policy.Validate(""), ErrPasswordIsRequired)
+	assert.ErrorIs(t, policy.Validate("Ra-1"), ErrPasswordTooShort)
+	assert.ErrorIs(t, policy.Validate("rafa-2024xy"), ErrPasswordMissingClass)
+	assert.ErrorIs(t, policy.Validate("RAFA-2024XY"), ErrPasswordMissingClass)
+	assert.ErrorIs(t, policy.Validate("Rafa-abcdxy"), ErrPasswordMissingClass)
+	assert.ErrorIs(t, policy.Validate("Rafa2024xyz"), ErrPasswordMissingClass)
+	assert.ErrorIs(t, policy.Validate(string(make([]byte, 73))), ErrPasswordTooLong)
+
+	// O tamanho conta caracteres, não bytes
+	assert.ErrorIs(t, PasswordPolicy{MinLength: 4}.Validate("çãé"), ErrPasswordTooShort)
+	assert.NoError(t, PasswordPolicy{MinLength: 4}.Validate("çãéô"))
+}
+
+func TestPasswordPolicy_MissingClassesAreListed(t *testing.T) {
+	err := PasswordPolicy{RequireUpper: true, RequireDigit: true}.Validate("rafael")
+	assert.EqualError(t, err, "Senha sem os tipos de caractere exigidos: inclua letra maiúscula, número")
+}
+
+func TestPasswordPolicy_RejectsCommonPasswords(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 6, RejectCommon: true}
+
+	assert.ErrorIs(t, policy.Validate("password123"), ErrPasswordTooCommon)
+	assert.ErrorIs(t, policy.Validate("PassWord123"), ErrPasswordTooCommon)
+	assert.ErrorIs(t, policy.Validate("123456"), ErrPasswordTooCommon)
+	assert.NoError(t, PasswordPolicy{MinLength: 6}.Validate("123456"))
+}
+
+func TestConfigurePasswordsRejectsInvalidCost(t *testing.T) {
+	assert.ErrorIs(t, ConfigurePasswords(DefaultPasswordPolicy, bcrypt.MinCost-1), ErrInvalidPasswordCost)
+	assert.ErrorIs(t, ConfigurePasswords(DefaultPasswordPolicy, bcrypt.MaxCost+1), ErrInvalidPasswordCost)
+}
+
+func TestNewUserEnforcesPolicy(t *testing.T) {
+	_, err := NewUser("Rafael", "rafael@gmail.com", "123456")
+	assert.ErrorIs(t, err, ErrPasswordTooShort)
+
+	_, err = NewUser("Rafael", "rafael@gmail.com", "password123")
+	assert.ErrorIs(t, err, ErrPasswordTooCommon)
+
+	user, _ := NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.ErrorIs(t, user.ChangePassword("semnumero"), ErrPasswordMissingClass)
+	assert.True(t, user.ValidatePassword("rafa2024x"))
+}
+
+func TestUser_NeedsRehash(t *testing.T) {
+	configurePasswords(t, DefaultPasswordPolicy, bcrypt.MinCost)
+	user, err := NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, err)
+	assert.False(t, user.NeedsRehash())
+
+	configurePasswords(t, DefaultPasswordPolicy, bcrypt.MinCost+1)
+	assert.True(t, user.NeedsRehash())
+
+	// Rehash não reaplica a política, que pode ter ficado mais rígida
+	configurePasswords(t, PasswordPolicy{MinLength: 20}, bcrypt.MinCost+1)
+	assert.NoError(t, user.Rehash("rafa2024x"))
+	assert.False(t, user.NeedsRehash())
+	assert.True(t, user.ValidatePassword("rafa2024x"))
+
+	cost, _ := bcrypt.Cost([]byte(user.Password))
+	assert.Equal(t, bcrypt.MinCost+1, cost)
+}
@@ -2,6 +2,7 @@ package entity
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
@@ -16,16 +17,18 @@ var (
 )
 
 type Product struct {
-	Id        entity.Id `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
+	Id    entity.Id `json:"id"`
+	Name  string    `json:"name"`
+	Price float64   `json:"price"`
+	// Produtos criados antes do controle de dono ficam sem OwnerId
+	OwnerId   *entity.Id `json:"owner_id"`
+	CreatedAt time.Time  `json:"created_at"`
 }
 
 func NewProduct(name string, price float64) (*Product, error) {
 	product := &Product{
 		Id:        entity.NewId(),
-		Name:      name,
+		Name:      strings.TrimSpace(name),
 		Price:     price,
 		CreatedAt: time.Now(),
 	}
@@ -48,7 +51,7 @@ func (p *Product) Validate() error {
 		return ErrInvalidId
 	}
 
-	if p.Name == "" {
+	if strings.TrimSpace(p.Name) == "" {
 		return ErrNameIsRequired
 	}
 
@@ -62,3 +65,11 @@ func (p *Product) Validate() error {
 
 	return nil
 }
+
+func (p *Product) SetOwner(userId entity.Id) {
+	p.OwnerId = &userId
+}
+
+func (p *Product) IsOwnedBy(userId string) bool {
+	return p.OwnerId != nil && p.OwnerId.String() == userId
+}
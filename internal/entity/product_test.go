@@ -21,6 +21,10 @@ func TestProductWhenNameIsRequired(t *testing.T) {
 	assert.Nil(t, p)
 	assert.Equal(t, ErrNameIsRequired, err)
 
+	p, err = NewProduct("   ", 10)
+	assert.Nil(t, p)
+	assert.Equal(t, ErrNameIsRequired, err)
+
 }
 
 func TestProductWhenPriceIsRequired(t *testing.T) {
@@ -44,3 +48,17 @@ func TestProductValidate(t *testing.T) {
 	assert.Nil(t, p.Validate())
 
 }
+
+func TestProductOwner(t *testing.T) {
+	p, err := NewProduct("Geladeira", 10)
+	assert.Nil(t, err)
+	assert.Nil(t, p.OwnerId)
+	assert.False(t, p.IsOwnedBy(""))
+
+	user, _ := NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	p.SetOwner(user.Id)
+	assert.True(t, p.IsOwnedBy(user.Id.String()))
+
+	other, _ := NewUser("Outro", "outro@gmail.com", "rafa2024x")
+	assert.False(t, p.IsOwnedBy(other.Id.String()))
+}
@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+)
+
+// RefreshToken guarda apenas o hash do token entregue ao cliente. Tokens
+// gerados a partir do mesmo login compartilham o FamilyId, o que permite
+// revogar a cadeia inteira quando um token já trocado é reutilizado.
+type RefreshToken struct {
+	Id           entity.Id  `json:"id"`
+	UserId       entity.Id  `json:"user_id"`
+	FamilyId     entity.Id  `json:"family_id"`
+	TokenHash    string     `json:"-"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedById *entity.Id `json:"replaced_by_id"`
+}
+
+// NewRefreshToken devolve o registro e o valor em texto puro, que só existe neste momento
+func NewRefreshToken(userId, familyId entity.Id, ttl time.Duration) (*RefreshToken, string, error) {
+	plain, err := randomToken()
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+
+	return &RefreshToken{
+		Id:        entity.NewId(),
+		UserId:    userId,
+		FamilyId:  familyId,
+		TokenHash: HashToken(plain),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, plain, nil
+}
+
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
+
+// HashToken é usado para gravar e procurar tokens opacos sem guardar o valor original
+func HashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
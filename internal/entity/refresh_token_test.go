@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRefreshToken(t *testing.T) {
+	userId, familyId := entity.NewId(), entity.NewId()
+	token, plain, err := NewRefreshToken(userId, familyId, time.Hour)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, plain)
+	assert.Equal(t, userId, token.UserId)
+	assert.Equal(t, familyId, token.FamilyId)
+	assert.Equal(t, HashToken(plain), token.TokenHash)
+	assert.NotEqual(t, plain, token.TokenHash)
+	assert.False(t, token.IsExpired())
+	assert.False(t, token.IsRevoked())
+
+	_, other, err := NewRefreshToken(userId, familyId, time.Hour)
+	assert.Nil(t, err)
+	assert.NotEqual(t, plain, other)
+}
+
+func TestRefreshTokenIsExpired(t *testing.T) {
+	token, _, err := NewRefreshToken(entity.NewId(), entity.NewId(), -time.Second)
+	assert.Nil(t, err)
+	assert.True(t, token.IsExpired())
+}
@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// RevokedToken é uma entrada da denylist de access tokens, identificados pelo jti.
+// Depois de ExpiresAt o token já seria recusado e a entrada pode ser apagada.
+type RevokedToken struct {
+	Jti       string    `json:"jti" gorm:"primaryKey"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,55 @@
+package entity
+
+import "errors"
+
+var ErrInvalidRole = errors.New("Papel inválido")
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+type Permission string
+
+const (
+	PermissionProductsRead   Permission = "products:read"
+	PermissionProductsWrite  Permission = "products:write"
+	PermissionProductsDelete Permission = "products:delete"
+	// Alterar e apagar produtos de outros usuários
+	PermissionProductsManageAny Permission = "products:manage_any"
+	PermissionUsersManage       Permission = "users:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermissionProductsRead,
+		PermissionProductsWrite,
+		PermissionProductsDelete,
+		PermissionProductsManageAny,
+		PermissionUsersManage,
+	},
+	RoleEditor: {
+		PermissionProductsRead,
+		PermissionProductsWrite,
+		PermissionProductsDelete,
+	},
+	RoleViewer: {
+		PermissionProductsRead,
+	},
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func RoleHasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,6 +1,9 @@
 package entity
 
 import (
+	"strings"
+	"time"
+
 	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -11,10 +14,13 @@ type User struct {
 	Email string    `json:"email"`
 	// Nunca sera exibido
 	Password string `json:"-"`
+	Role     string `json:"role"`
+	// Nulo até o dono confirmar o e-mail
+	VerifiedAt *time.Time `json:"verified_at"`
 }
 
 func NewUser(name, email, password string) (*User, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := hashPassword(password)
 
 	if err != nil {
 		return nil, err
@@ -22,11 +28,110 @@ func NewUser(name, email, password string) (*User, error) {
 	return &User{
 		Id:       entity.NewId(),
 		Name:     name,
-		Email:    email,
-		Password: string(hash),
+		Email:    NormalizeEmail(email),
+		Password: hash,
+		Role:     RoleViewer,
 	}, nil
 }
 
+// NormalizeEmail deixa o e-mail no formato gravado no banco, sem espaços e em minúsculas
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (u *User) SetRole(role string) error {
+	if !IsValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	u.Role = role
+	return nil
+}
+
+func (u *User) IsVerified() bool {
+	return u.VerifiedAt != nil
+}
+
+func (u *User) MarkVerified() {
+	if u.VerifiedAt == nil {
+		now := time.Now()
+		u.VerifiedAt = &now
+	}
+}
+
+// ChangeEmail troca o e-mail e, se ele mudou, exige uma nova verificação
+func (u *User) ChangeEmail(email string) {
+	email = NormalizeEmail(email)
+
+	if email != u.Email {
+		u.Email = email
+		u.VerifiedAt = nil
+	}
+}
+
+func (u *User) Can(permission Permission) bool {
+	return RoleHasPermission(u.Role, permission)
+}
+
+// ChangePassword troca o hash gravado pelo da nova senha
+func (u *User) ChangePassword(password string) error {
+	hash, err := hashPassword(password)
+
+	if err != nil {
+		return err
+	}
+
+	u.Password = hash
+	return nil
+}
+
+// Rehash troca o hash pelo custo configurado sem passar pela política, que
+// pode ter ficado mais rígida depois que a senha foi cadastrada
+func (u *User) Rehash(password string) error {
+	_, cost := currentPasswordSettings()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+
+	if err != nil {
+		return err
+	}
+
+	u.Password = string(hash)
+	return nil
+}
+
+// NeedsRehash indica que o hash foi gerado com custo menor que o configurado
+func (u *User) NeedsRehash() bool {
+	current, err := bcrypt.Cost([]byte(u.Password))
+
+	if err != nil {
+		return false
+	}
+
+	_, cost := currentPasswordSettings()
+	return current < cost
+}
+
+// NewExternalUser cria a conta de quem entrou por um provedor de identidade.
+// Ela nasce sem senha; o dono pode definir uma pela redefinição de senha.
+func NewExternalUser(name, email string) *User {
+	return &User{
+		Id:    entity.NewId(),
+		Name:  name,
+		Email: NormalizeEmail(email),
+		Role:  RoleViewer,
+	}
+}
+
+func (u *User) HasPassword() bool {
+	return u.Password != ""
+}
+
+// ClearPassword invalida a senha atual, deixando a conta acessível só pelo
+// provedor de identidade ou por uma redefinição de senha
+func (u *User) ClearPassword() {
+	u.Password = ""
+}
+
 func (u *User) ValidatePassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
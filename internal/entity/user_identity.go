@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+)
+
+// UserIdentity liga um usuário à conta dele em um provedor OIDC. O "sub" do
+// provedor não muda, então o vínculo continua valendo se o e-mail for trocado.
+type UserIdentity struct {
+	Id        entity.Id `json:"id"`
+	UserId    entity.Id `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewUserIdentity(userId entity.Id, provider, subject, email string) *UserIdentity {
+	return &UserIdentity{
+		Id:        entity.NewId(),
+		UserId:    userId,
+		Provider:  provider,
+		Subject:   subject,
+		Email:     NormalizeEmail(email),
+		CreatedAt: time.Now(),
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func TestNewUser(t *testing.T) {
-	user, err := NewUser("Rafael", "rafael@gmail.com", "123456")
+	user, err := NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
 	assert.Nil(t, err)
 	assert.NotNil(t, user)
 	assert.NotEmpty(t, user.Id)
@@ -17,10 +17,74 @@ func TestNewUser(t *testing.T) {
 }
 
 func TestUser_ValidatePassword(t *testing.T) {
-	user, err := NewUser("Rafael", "rafael@gmail.com", "123456")
+	user, err := NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
 	assert.Nil(t, err)
-	assert.True(t, user.ValidatePassword("123456"))
-	assert.False(t, user.ValidatePassword("1234567"))
-	assert.NotEqual(t, "123456", user.Password)
+	assert.True(t, user.ValidatePassword("rafa2024x"))
+	assert.False(t, user.ValidatePassword("rafa2024y"))
+	assert.NotEqual(t, "rafa2024x", user.Password)
 
 }
+
+func TestNewUserIsViewer(t *testing.T) {
+	user, err := NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.Nil(t, err)
+	assert.Equal(t, RoleViewer, user.Role)
+	assert.True(t, user.Can(PermissionProductsRead))
+	assert.False(t, user.Can(PermissionProductsWrite))
+}
+
+func TestUser_SetRole(t *testing.T) {
+	user, _ := NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+
+	assert.Nil(t, user.SetRole(RoleEditor))
+	assert.True(t, user.Can(PermissionProductsWrite))
+	assert.False(t, user.Can(PermissionUsersManage))
+
+	assert.Nil(t, user.SetRole(RoleAdmin))
+	assert.True(t, user.Can(PermissionUsersManage))
+
+	assert.Equal(t, ErrInvalidRole, user.SetRole("root"))
+	assert.Equal(t, RoleAdmin, user.Role)
+}
+
+func TestNewUserNormalizesEmail(t *testing.T) {
+	user, err := NewUser("Rafael", "  Rafael@Gmail.COM ", "rafa2024x")
+	assert.Nil(t, err)
+	assert.Equal(t, "rafael@gmail.com", user.Email)
+}
+
+func TestUser_Verification(t *testing.T) {
+	user, _ := NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.False(t, user.IsVerified())
+
+	user.MarkVerified()
+	assert.True(t, user.IsVerified())
+	verifiedAt := user.VerifiedAt
+
+	user.MarkVerified()
+	assert.Equal(t, verifiedAt, user.VerifiedAt)
+
+	// O mesmo e-mail, em outra grafia, continua verificado
+	user.ChangeEmail(" Rafael@Gmail.com")
+	assert.True(t, user.IsVerified())
+
+	user.ChangeEmail("outro@gmail.com")
+	assert.Equal(t, "outro@gmail.com", user.Email)
+	assert.False(t, user.IsVerified())
+}
+
+func TestNewExternalUser(t *testing.T) {
+	user := NewExternalUser("Rafael", " Rafael@Gmail.com")
+	assert.NotEmpty(t, user.Id)
+	assert.Equal(t, "rafael@gmail.com", user.Email)
+	assert.Equal(t, RoleViewer, user.Role)
+	assert.False(t, user.HasPassword())
+	assert.False(t, user.ValidatePassword(""))
+	assert.False(t, user.NeedsRehash())
+
+	assert.NoError(t, user.ChangePassword("rafa2024x"))
+	assert.True(t, user.HasPassword())
+
+	user.ClearPassword()
+	assert.False(t, user.ValidatePassword("rafa2024x"))
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+)
+
+var ErrInvalidAPIKey = errors.New("API key inválida")
+
+// Intervalo mínimo entre duas gravações de last_used_at da mesma chave, para
+// que um job com muitas requisições não gere uma escrita por chamada
+const apiKeyTouchInterval = time.Minute
+
+type APIKeyService struct {
+	Keys  database.APIKeyInterface
+	Users database.UserInterface
+	// Opcional; com ele as chaves de quem não verificou o e-mail são recusadas
+	Verification *VerificationService
+	now          func() time.Time
+}
+
+func NewAPIKeyService(keys database.APIKeyInterface, users database.UserInterface) *APIKeyService {
+	return &APIKeyService{
+		Keys:  keys,
+		Users: users,
+		now:   time.Now,
+	}
+}
+
+// Create devolve a chave em texto puro, que não pode ser recuperada depois
+func (s *APIKeyService) Create(ctx context.Context, user *entity.User, name string) (*entity.APIKey, string, error) {
+	key, plain, err := entity.NewAPIKey(user.Id, name)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.Keys.WithContext(ctx).Create(key); err != nil {
+		return nil, "", err
+	}
+
+	return key, plain, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, user *entity.User) ([]entity.APIKey, error) {
+	return s.Keys.WithContext(ctx).FindByUser(user.Id)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, user *entity.User, id string) error {
+	return s.Keys.WithContext(ctx).Revoke(user.Id, id)
+}
+
+// Authenticate devolve o dono da chave. O papel vem do usuário, então uma
+// mudança de papel vale também para as chaves já emitidas.
+func (s *APIKeyService) Authenticate(ctx context.Context, plain string) (*entity.User, error) {
+	keys := s.Keys.WithContext(ctx)
+	key, err := keys.FindByHash(entity.HashToken(plain))
+
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if key.IsRevoked() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.Users.WithContext(ctx).FindById(key.UserId.String())
+
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Verification != nil && !s.Verification.CanLogin(user) {
+		return nil, ErrEmailNotVerified
+	}
+
+	s.touch(keys, key)
+
+	return user, nil
+}
+
+// touch registra o uso da chave. Uma falha aqui não deve barrar a requisição.
+func (s *APIKeyService) touch(keys database.APIKeyInterface, key *entity.APIKey) {
+	now := s.now()
+
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < apiKeyTouchInterval {
+		return
+	}
+
+	if err := keys.Touch(key.Id, now); err != nil {
+		log.Printf("registrando uso da API key %s: %v", key.Id, err)
+	}
+}
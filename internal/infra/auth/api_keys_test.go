@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAPIKeyService(t *testing.T) (*APIKeyService, *fakeClock) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&entity.User{}, &entity.APIKey{}); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	service := NewAPIKeyService(database.NewAPIKey(db), database.NewUser(db))
+	service.now = clock.Now
+
+	return service, clock
+}
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	service, _ := setupAPIKeyService(t)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, service.Users.Create(user))
+
+	key, plain, err := service.Create(context.Background(), user, "importador")
+	assert.NoError(t, err)
+
+	found, err := service.Authenticate(context.Background(), plain)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id, found.Id)
+
+	_, err = service.Authenticate(context.Background(), "ak_desconhecida")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	assert.NoError(t, service.Revoke(context.Background(), user, key.Id.String()))
+	_, err = service.Authenticate(context.Background(), plain)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	keys, _ := service.List(context.Background(), user)
+	assert.Empty(t, keys)
+}
+
+func TestAPIKeyAuthenticateRecordsLastUse(t *testing.T) {
+	service, clock := setupAPIKeyService(t)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, service.Users.Create(user))
+	key, plain, _ := service.Create(context.Background(), user, "importador")
+
+	lastUsed := func() time.Time {
+		found, _ := service.Keys.FindByHash(key.KeyHash)
+		return *found.LastUsedAt
+	}
+
+	first := clock.now
+	service.Authenticate(context.Background(), plain)
+	assert.True(t, first.Equal(lastUsed()))
+
+	// Dentro do intervalo o uso não é gravado de novo
+	clock.now = clock.now.Add(30 * time.Second)
+	service.Authenticate(context.Background(), plain)
+	assert.True(t, first.Equal(lastUsed()))
+
+	clock.now = clock.now.Add(time.Minute)
+	service.Authenticate(context.Background(), plain)
+	assert.True(t, clock.now.Equal(lastUsed()))
+}
+
+func TestAPIKeyAuthenticateDeletedUser(t *testing.T) {
+	service, _ := setupAPIKeyService(t)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	_, plain, _ := service.Create(context.Background(), user, "importador")
+
+	_, err := service.Authenticate(context.Background(), plain)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAPIKeyAuthenticateRequiresVerifiedEmail(t *testing.T) {
+	service, _ := setupAPIKeyService(t)
+	service.Verification = &VerificationService{Required: true}
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	user.MarkVerified()
+	assert.NoError(t, service.Users.Create(user))
+	_, plain, _ := service.Create(context.Background(), user, "importador")
+
+	_, err := service.Authenticate(context.Background(), plain)
+	assert.NoError(t, err)
+
+	user.ChangeEmail("novo@gmail.com")
+	assert.NoError(t, service.Users.Update(user))
+
+	_, err = service.Authenticate(context.Background(), plain)
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+}
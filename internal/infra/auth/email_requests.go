@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// EmailLimits limita os pedidos que mandam e-mail para um endereço, a
+// redefinição de senha e o reenvio da verificação, com os bloqueios
+// progressivos do login. Todo pedido conta, exista a conta ou não, para que o
+// bloqueio não revele quais e-mails estão cadastrados.
+type EmailLimits struct {
+	Email *LoginLimiter
+	IP    *LoginLimiter
+}
+
+func NewEmailLimits(email, ip *LoginLimiter) *EmailLimits {
+	return &EmailLimits{Email: email, IP: ip}
+}
+
+// Allow registra o pedido ou devolve *LockedError se a cota acabou. Sem
+// limites (nil) tudo é aceito.
+func (l *EmailLimits) Allow(email, ip string) error {
+	if l == nil {
+		return nil
+	}
+
+	if retryAfter := max(l.Email.RetryAfter(email), l.IP.RetryAfter(ip)); retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+
+	l.Email.Fail(email)
+	l.IP.Fail(ip)
+
+	return nil
+}
+
+// mailQueue faz a consulta e o envio fora da requisição, para que a resposta
+// saia no mesmo tempo com ou sem conta cadastrada
+type mailQueue struct {
+	wg sync.WaitGroup
+}
+
+// run chama fn em segundo plano com um contexto que não é cancelado junto com
+// a requisição, mas mantém o trace. Erros só podem ir para o log.
+func (q *mailQueue) run(ctx context.Context, what string, fn func(ctx context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+	q.wg.Add(1)
+
+	go func() {
+		defer q.wg.Done()
+
+		if err := fn(ctx); err != nil {
+			log.Printf("%s: %v", what, err)
+		}
+	}()
+}
+
+func (q *mailQueue) wait() {
+	q.wg.Wait()
+}
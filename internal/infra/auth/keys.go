@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+var (
+	ErrUnsupportedKey   = errors.New("chave não suportada, use RSA (RS256) ou Ed25519 (EdDSA)")
+	ErrNoSigningKey     = errors.New("chave de assinatura não encontrada")
+	ErrUnknownKey       = errors.New("token assinado por chave desconhecida")
+	ErrAlgorithmInvalid = errors.New("algoritmo do token não corresponde à chave")
+)
+
+// Chaves RSA menores que isso não são aceitas
+const minRSABits = 2048
+
+// TokenCodec assina e valida os access tokens. O *jwtauth.JWTAuth também o implementa.
+type TokenCodec interface {
+	Encode(claims map[string]interface{}) (jwt.Token, string, error)
+	Decode(tokenString string) (jwt.Token, error)
+}
+
+// SigningKey é uma chave identificada pelo kid. Chaves só com a parte pública
+// servem para validar tokens emitidos antes de uma rotação.
+type SigningKey struct {
+	Id        string
+	Algorithm jwa.SignatureAlgorithm
+	Private   interface{}
+	Public    interface{}
+}
+
+// KeySet assina com uma chave e valida com todas as que conhece, escolhendo pelo kid
+type KeySet struct {
+	Signing    *SigningKey
+	Validation TokenValidation
+	keys       map[string]*SigningKey
+}
+
+// O jwx recusa um nbf igual ao segundo atual quando não há tolerância, o que
+// derrubaria tokens recém-emitidos
+const minLeeway = time.Second
+
+// TokenValidation são as regras das claims dos tokens recebidos. Com Issuer ou
+// Audience vazios a claim correspondente não é conferida.
+type TokenValidation struct {
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
+func (v TokenValidation) options() []jwt.ParseOption {
+	options := []jwt.ParseOption{jwt.WithValidate(true), jwt.WithAcceptableSkew(max(v.Leeway, minLeeway))}
+
+	// jwt.WithIssuer aceita tokens sem iss, então a claim é exigida pelo valor
+	if v.Issuer != "" {
+		options = append(options, jwt.WithClaimValue(jwt.IssuerKey, v.Issuer))
+	}
+
+	if v.Audience != "" {
+		options = append(options, jwt.WithAudience(v.Audience))
+	}
+
+	return options
+}
+
+// NewKeySet usa a chave signingKid para assinar; ela precisa ter a parte privada
+func NewKeySet(signingKid string, keys ...*SigningKey) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*SigningKey, len(keys))}
+
+	for _, key := range keys {
+		if _, ok := set.keys[key.Id]; ok {
+			return nil, fmt.Errorf("kid %q repetido", key.Id)
+		}
+
+		set.keys[key.Id] = key
+	}
+
+	signing, ok := set.keys[signingKid]
+
+	if !ok || signing.Private == nil {
+		return nil, fmt.Errorf("%w: kid %q", ErrNoSigningKey, signingKid)
+	}
+
+	set.Signing = signing
+
+	return set, nil
+}
+
+// NewHMACKeySet mantém a assinatura HS256 com segredo compartilhado e sem kid
+func NewHMACKeySet(secret []byte) *KeySet {
+	key := &SigningKey{Algorithm: jwa.HS256, Private: secret, Public: secret}
+	set, _ := NewKeySet("", key)
+
+	return set
+}
+
+// LoadKeySet lê os arquivos <kid>.pem de dir. Cada arquivo pode ter a chave
+// privada ou só a pública; signingKid escolhe qual assina os novos tokens.
+func LoadKeySet(dir, signingKid string) (*KeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	keys := make([]*SigningKey, 0, len(files))
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := ParsePEMKey(strings.TrimSuffix(filepath.Base(file), ".pem"), data)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(signingKid, keys...)
+}
+
+// ParsePEMKey aceita chaves RSA (PKCS#1 ou PKCS#8) e Ed25519 (PKCS#8), privadas ou públicas
+func ParsePEMKey(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		return nil, errors.New("PEM inválido")
+	}
+
+	var (
+		parsed interface{}
+		err    error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: bloco %q", ErrUnsupportedKey, block.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{Id: kid}
+
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.Private = signer
+		key.Public = signer.Public()
+	} else {
+		key.Public = parsed
+	}
+
+	switch public := key.Public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("%w: RSA com menos de %d bits", ErrUnsupportedKey, minRSABits)
+		}
+
+		key.Algorithm = jwa.RS256
+	case ed25519.PublicKey:
+		key.Algorithm = jwa.EdDSA
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key.Public)
+	}
+
+	return key, nil
+}
+
+func (s *KeySet) Encode(claims map[string]interface{}) (jwt.Token, string, error) {
+	token := jwt.New()
+
+	for name, value := range claims {
+		if err := token.Set(name, value); err != nil {
+			return nil, "", err
+		}
+	}
+
+	headers := jws.NewHeaders()
+
+	if s.Signing.Id != "" {
+		if err := headers.Set(jws.KeyIDKey, s.Signing.Id); err != nil {
+			return nil, "", err
+		}
+	}
+
+	signed, err := jwt.Sign(token, s.Signing.Algorithm, s.Signing.Private, jwt.WithHeaders(headers))
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return token, string(signed), nil
+}
+
+// Decode confere a assinatura com a chave do kid e valida exp, nbf, iat e as
+// regras de Validation. O alg do cabeçalho precisa ser o da chave, para que um
+// token não escolha como será verificado.
+func (s *KeySet) Decode(tokenString string) (jwt.Token, error) {
+	message, err := jws.ParseString(tokenString)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(message.Signatures()) != 1 {
+		return nil, errors.New("token deve ter exatamente uma assinatura")
+	}
+
+	headers := message.Signatures()[0].ProtectedHeaders()
+	key, ok := s.keys[headers.KeyID()]
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if headers.Algorithm() != key.Algorithm {
+		return nil, ErrAlgorithmInvalid
+	}
+
+	return jwt.ParseString(tokenString, append(s.Validation.options(), jwt.WithVerify(key.Algorithm, key.Public))...)
+}
+
+// JWKS publica as chaves públicas para que outros serviços validem os tokens.
+// Segredos HMAC nunca entram no conjunto.
+func (s *KeySet) JWKS() (jwk.Set, error) {
+	set := jwk.NewSet()
+	ids := make([]string, 0, len(s.keys))
+
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		key := s.keys[id]
+
+		if key.Algorithm == jwa.HS256 {
+			continue
+		}
+
+		public, err := jwk.New(key.Public)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for name, value := range map[string]interface{}{
+			jwk.KeyIDKey:     key.Id,
+			jwk.AlgorithmKey: key.Algorithm,
+			jwk.KeyUsageKey:  jwk.ForSignature,
+		} {
+			if err := public.Set(name, value); err != nil {
+				return nil, err
+			}
+		}
+
+		set.Add(public)
+	}
+
+	return set, nil
+}
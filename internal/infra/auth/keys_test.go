@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writePrivateKey(t *testing.T, dir, kid string, key interface{}) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writePEM(t, dir, kid, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, dir, kid string, key interface{}) {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writePEM(t, dir, kid, "PUBLIC KEY", der)
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func claimsFor(sub string) map[string]interface{} {
+	return map[string]interface{}{"sub": sub, "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestKeySetSignsWithKid(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	writePrivateKey(t, dir, "rsa-2024", newRSAKey(t))
+	writePrivateKey(t, dir, "ed-2025", edKey)
+
+	for kid, alg := range map[string]jwa.SignatureAlgorithm{"rsa-2024": jwa.RS256, "ed-2025": jwa.EdDSA} {
+		keys, err := LoadKeySet(dir, kid)
+		assert.NoError(t, err)
+
+		_, signed, err := keys.Encode(claimsFor("1"))
+		assert.NoError(t, err)
+
+		message, _ := jws.ParseString(signed)
+		headers := message.Signatures()[0].ProtectedHeaders()
+		assert.Equal(t, kid, headers.KeyID())
+		assert.Equal(t, alg, headers.Algorithm())
+
+		token, err := keys.Decode(signed)
+		assert.NoError(t, err)
+		assert.Equal(t, "1", token.Subject())
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	dir := t.TempDir()
+	old := newRSAKey(t)
+	writePrivateKey(t, dir, "2024", old)
+
+	before, err := LoadKeySet(dir, "2024")
+	assert.NoError(t, err)
+	_, issued, _ := before.Encode(claimsFor("1"))
+
+	// A chave antiga fica só com a parte pública até os tokens dela expirarem
+	_, next, _ := ed25519.GenerateKey(rand.Reader)
+	writePublicKey(t, dir, "2024", &old.PublicKey)
+	writePrivateKey(t, dir, "2025", next)
+
+	after, err := LoadKeySet(dir, "2025")
+	assert.NoError(t, err)
+
+	_, err = after.Decode(issued)
+	assert.NoError(t, err)
+
+	_, err = LoadKeySet(dir, "2024")
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+
+	// Depois de removida, a chave antiga não valida mais nada
+	os.Remove(filepath.Join(dir, "2024.pem"))
+	after, _ = LoadKeySet(dir, "2025")
+	_, err = after.Decode(issued)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeySetRejectsForeignTokens(t *testing.T) {
+	dir := t.TempDir()
+	key := newRSAKey(t)
+	writePrivateKey(t, dir, "rsa", key)
+	keys, _ := LoadKeySet(dir, "rsa")
+
+	// Mesmo kid, mas assinado com HS256 usando a chave pública como segredo
+	publicDER, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	headers := jws.NewHeaders()
+	headers.Set(jws.KeyIDKey, "rsa")
+	token := jwt.New()
+	token.Set("sub", "1")
+	forged, _ := jwt.Sign(token, jwa.HS256, publicDER, jwt.WithHeaders(headers))
+
+	_, err := keys.Decode(string(forged))
+	assert.ErrorIs(t, err, ErrAlgorithmInvalid)
+
+	// Sem kid não há como escolher a chave
+	_, unsigned, _ := NewHMACKeySet([]byte("secret")).Encode(claimsFor("1"))
+	_, err = keys.Decode(unsigned)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	_, expired, _ := keys.Encode(map[string]interface{}{"sub": "1", "exp": time.Now().Add(-time.Minute).Unix()})
+	_, err = keys.Decode(expired)
+	assert.EqualError(t, err, "exp not satisfied")
+}
+
+func TestKeySetValidation(t *testing.T) {
+	keys := NewHMACKeySet([]byte("secret"))
+	keys.Validation = TokenValidation{Issuer: "https://api.example.com", Audience: "products", Leeway: 30 * time.Second}
+	now := time.Now()
+
+	decode := func(claims map[string]interface{}) error {
+		_, signed, _ := keys.Encode(claims)
+		_, err := keys.Decode(signed)
+
+		return err
+	}
+
+	valid := map[string]interface{}{"sub": "1", "iss": "https://api.example.com", "aud": []string{"products", "admin"}, "iat": now.Unix(), "nbf": now.Unix(), "exp": now.Add(time.Minute).Unix()}
+	assert.NoError(t, decode(valid))
+
+	for name, claims := range map[string]map[string]interface{}{
+		"sem iss":     {"sub": "1", "aud": "products"},
+		"outro iss":   {"sub": "1", "iss": "https://outro.example.com", "aud": "products"},
+		"sem aud":     {"sub": "1", "iss": "https://api.example.com"},
+		"outro aud":   {"sub": "1", "iss": "https://api.example.com", "aud": "admin"},
+		"nbf futuro":  {"sub": "1", "iss": "https://api.example.com", "aud": "products", "nbf": now.Add(time.Minute).Unix()},
+		"exp vencido": {"sub": "1", "iss": "https://api.example.com", "aud": "products", "exp": now.Add(-time.Minute).Unix()},
+	} {
+		assert.Error(t, decode(claims), name)
+	}
+
+	// A tolerância cobre a diferença de relógio entre servidores
+	valid["nbf"] = now.Add(10 * time.Second).Unix()
+	assert.NoError(t, decode(valid))
+}
+
+func TestParsePEMKeyRejectsUnsupportedKeys(t *testing.T) {
+	small, _ := rsa.GenerateKey(rand.Reader, 1024)
+	der, _ := x509.MarshalPKCS8PrivateKey(small)
+	_, err := ParsePEMKey("small", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+
+	_, err = ParsePEMKey("cert", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}}))
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+
+	_, err = ParsePEMKey("vazio", []byte("não é PEM"))
+	assert.Error(t, err)
+
+	der = x509.MarshalPKCS1PrivateKey(newRSAKey(t))
+	key, err := ParsePEMKey("pkcs1", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}))
+	assert.NoError(t, err)
+	assert.Equal(t, jwa.RS256, key.Algorithm)
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	writePrivateKey(t, dir, "a", newRSAKey(t))
+	writePrivateKey(t, dir, "b", edKey)
+	keys, _ := LoadKeySet(dir, "b")
+
+	set, err := keys.JWKS()
+	assert.NoError(t, err)
+
+	data, _ := json.Marshal(set)
+	var published struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	json.Unmarshal(data, &published)
+
+	assert.Len(t, published.Keys, 2)
+	assert.Equal(t, "a", published.Keys[0]["kid"])
+	assert.Equal(t, "RS256", published.Keys[0]["alg"])
+	assert.Equal(t, "sig", published.Keys[0]["use"])
+	assert.Equal(t, "b", published.Keys[1]["kid"])
+	assert.Equal(t, "EdDSA", published.Keys[1]["alg"])
+
+	for _, key := range published.Keys {
+		assert.NotContains(t, key, "d")
+	}
+
+	empty, _ := NewHMACKeySet([]byte("secret")).JWKS()
+	assert.Equal(t, 0, empty.Len())
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidCredentials = errors.New("credenciais inválidas")
+
+// LockedError indica que o e-mail ou o IP está bloqueado por excesso de falhas
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("muitas tentativas, tente novamente em %s", e.RetryAfter.Round(time.Second))
+}
+
+// LoginLimiter conta as falhas de login por chave. A partir de MaxAttempts
+// falhas seguidas a chave fica bloqueada por BaseLockout, tempo que dobra a
+// cada nova falha até MaxLockout. Os contadores ficam em memória, por instância.
+type LoginLimiter struct {
+	MaxAttempts int
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+
+	mu       sync.Mutex
+	attempts map[string]*loginAttempts
+	now      func() time.Time
+}
+
+type loginAttempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func NewLoginLimiter(maxAttempts int, baseLockout, maxLockout time.Duration) *LoginLimiter {
+	return &LoginLimiter{
+		MaxAttempts: maxAttempts,
+		BaseLockout: baseLockout,
+		MaxLockout:  maxLockout,
+		attempts:    map[string]*loginAttempts{},
+		now:         time.Now,
+	}
+}
+
+// Configure troca os limites mantendo as falhas já contadas
+func (l *LoginLimiter) Configure(maxAttempts int, baseLockout, maxLockout time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.MaxAttempts = maxAttempts
+	l.BaseLockout = baseLockout
+	l.MaxLockout = maxLockout
+}
+
+// RetryAfter devolve quanto falta para a chave ser liberada, ou zero
+func (l *LoginLimiter) RetryAfter(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attempts, ok := l.attempts[key]
+
+	if !ok {
+		return 0
+	}
+
+	return max(attempts.lockedUntil.Sub(l.now()), 0)
+}
+
+func (l *LoginLimiter) Fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	attempts, ok := l.attempts[key]
+
+	if !ok || l.stale(attempts, now) {
+		attempts = &loginAttempts{}
+		l.attempts[key] = attempts
+	}
+
+	attempts.failures++
+	attempts.lastFailure = now
+
+	if extra := attempts.failures - l.MaxAttempts; extra >= 0 {
+		lockout := l.BaseLockout
+
+		for i := 0; i < extra && lockout < l.MaxLockout; i++ {
+			lockout *= 2
+		}
+
+		attempts.lockedUntil = now.Add(min(lockout, l.MaxLockout))
+	}
+}
+
+func (l *LoginLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+}
+
+// Purge descarta os contadores esquecidos, para que o mapa não cresça sem limite
+func (l *LoginLimiter) Purge() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	purged := 0
+
+	for key, attempts := range l.attempts {
+		if l.stale(attempts, now) {
+			delete(l.attempts, key)
+			purged++
+		}
+	}
+
+	return purged
+}
+
+// stale diz se as falhas já são antigas o bastante para serem esquecidas
+func (l *LoginLimiter) stale(attempts *loginAttempts, now time.Time) bool {
+	return now.After(attempts.lockedUntil) && now.Sub(attempts.lastFailure) > l.MaxLockout
+}
+
+// LoginService confere as credenciais aplicando os limites por e-mail e por IP
+type LoginService struct {
+	Users       database.UserInterface
+	EmailLimits *LoginLimiter
+	IPLimits    *LoginLimiter
+}
+
+func NewLoginService(users database.UserInterface, emailLimits, ipLimits *LoginLimiter) *LoginService {
+	// Gera já o hash falso, para o primeiro e-mail desconhecido não demorar o dobro
+	dummyHash()
+
+	return &LoginService{
+		Users:       users,
+		EmailLimits: emailLimits,
+		IPLimits:    ipLimits,
+	}
+}
+
+// Authenticate devolve ErrInvalidCredentials tanto para e-mail desconhecido
+// quanto para senha errada, gastando o mesmo tempo de bcrypt nos dois casos
+func (s *LoginService) Authenticate(ctx context.Context, email, password, ip string) (*entity.User, error) {
+	email = entity.NormalizeEmail(email)
+
+	if retryAfter := max(s.EmailLimits.RetryAfter(email), s.IPLimits.RetryAfter(ip)); retryAfter > 0 {
+		return nil, &LockedError{RetryAfter: retryAfter}
+	}
+
+	users := s.Users.WithContext(ctx)
+	user, err := users.FindByEmail(email)
+
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return nil, err
+	}
+
+	// Contas sem senha (só login externo) gastam o mesmo tempo de uma inexistente
+	if user == nil || !user.HasPassword() {
+		compareDummyPassword(password)
+	}
+
+	if user == nil || !user.ValidatePassword(password) {
+		s.EmailLimits.Fail(email)
+		s.IPLimits.Fail(ip)
+
+		return nil, ErrInvalidCredentials
+	}
+
+	// Só o contador do e-mail é zerado: uma conta válida não libera o IP
+	s.EmailLimits.Reset(email)
+
+	if user.NeedsRehash() {
+		rehash(users, user, password)
+	}
+
+	return user, nil
+}
+
+// rehash atualiza hashes antigos aproveitando a senha em claro do login. Uma
+// falha aqui não deve impedir o acesso, então só é registrada.
+func rehash(users database.UserInterface, user *entity.User, password string) {
+	if err := user.Rehash(password); err != nil {
+		log.Printf("atualizando hash da senha de %s: %v", user.Id, err)
+		return
+	}
+
+	if err := users.Update(user); err != nil {
+		log.Printf("atualizando hash da senha de %s: %v", user.Id, err)
+	}
+}
+
+var dummy = struct {
+	sync.Mutex
+	hash []byte
+}{}
+
+// compareDummyPassword gasta o tempo de uma comparação real para que a
+// resposta não revele se o e-mail está cadastrado. O hash acompanha o custo
+// configurado em entity.ConfigurePasswords; com outro custo o tempo denunciaria.
+func compareDummyPassword(password string) {
+	bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+}
+
+func dummyHash() []byte {
+	cost := entity.PasswordCost()
+
+	dummy.Lock()
+	defer dummy.Unlock()
+
+	if current, err := bcrypt.Cost(dummy.hash); err != nil || current != cost {
+		dummy.hash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), cost)
+	}
+
+	return dummy.hash
+}
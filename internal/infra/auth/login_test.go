@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestLimiter(maxAttempts int) (*LoginLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewLoginLimiter(maxAttempts, time.Second, 10*time.Second)
+	limiter.now = clock.Now
+
+	return limiter, clock
+}
+
+func TestLoginLimiterExponentialLockout(t *testing.T) {
+	limiter, clock := newTestLimiter(3)
+
+	limiter.Fail("rafael@gmail.com")
+	limiter.Fail("rafael@gmail.com")
+	assert.Zero(t, limiter.RetryAfter("rafael@gmail.com"))
+
+	limiter.Fail("rafael@gmail.com")
+	assert.Equal(t, time.Second, limiter.RetryAfter("rafael@gmail.com"))
+
+	limiter.Fail("rafael@gmail.com")
+	assert.Equal(t, 2*time.Second, limiter.RetryAfter("rafael@gmail.com"))
+
+	limiter.Fail("rafael@gmail.com")
+	assert.Equal(t, 4*time.Second, limiter.RetryAfter("rafael@gmail.com"))
+
+	for i := 0; i < 100; i++ {
+		limiter.Fail("rafael@gmail.com")
+	}
+
+	assert.Equal(t, 10*time.Second, limiter.RetryAfter("rafael@gmail.com"))
+	assert.Zero(t, limiter.RetryAfter("outro@gmail.com"))
+
+	clock.now = clock.now.Add(10 * time.Second)
+	assert.Zero(t, limiter.RetryAfter("rafael@gmail.com"))
+}
+
+func TestLoginLimiterForgetsOldFailures(t *testing.T) {
+	limiter, clock := newTestLimiter(3)
+
+	limiter.Fail("rafael@gmail.com")
+	limiter.Fail("rafael@gmail.com")
+
+	clock.now = clock.now.Add(11 * time.Second)
+	assert.Equal(t, 1, limiter.Purge())
+
+	limiter.Fail("rafael@gmail.com")
+	assert.Zero(t, limiter.RetryAfter("rafael@gmail.com"))
+}
+
+func TestLoginLimiterReset(t *testing.T) {
+	limiter, _ := newTestLimiter(1)
+
+	limiter.Fail("rafael@gmail.com")
+	assert.NotZero(t, limiter.RetryAfter("rafael@gmail.com"))
+
+	limiter.Reset("rafael@gmail.com")
+	assert.Zero(t, limiter.RetryAfter("rafael@gmail.com"))
+}
+
+func TestLoginLimiterConfigure(t *testing.T) {
+	limiter, _ := newTestLimiter(3)
+
+	limiter.Fail("rafael@gmail.com")
+	assert.Zero(t, limiter.RetryAfter("rafael@gmail.com"))
+
+	// As falhas já contadas valem para o novo limite
+	limiter.Configure(2, time.Minute, time.Hour)
+	limiter.Fail("rafael@gmail.com")
+	assert.Equal(t, time.Minute, limiter.RetryAfter("rafael@gmail.com"))
+}
+
+func setupLoginService(t *testing.T) *LoginService {
+	tokens := setupTokenService(t)
+	emailLimits, _ := newTestLimiter(2)
+	ipLimits, _ := newTestLimiter(3)
+
+	return NewLoginService(tokens.Users, emailLimits, ipLimits)
+}
+
+func TestAuthenticate(t *testing.T) {
+	service := setupLoginService(t)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, service.Users.Create(user))
+
+	found, err := service.Authenticate(context.Background(), "Rafael@Gmail.com", "rafa2024x", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id, found.Id)
+
+	// E-mail desconhecido e senha errada recebem o mesmo erro
+	_, err = service.Authenticate(context.Background(), "ninguem@gmail.com", "rafa2024x", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = service.Authenticate(context.Background(), "rafael@gmail.com", "errada", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestAuthenticateExternalUser(t *testing.T) {
+	service := setupLoginService(t)
+	user := entity.NewExternalUser("Rafael", "rafael@gmail.com")
+	assert.NoError(t, service.Users.Create(user))
+
+	// Conta criada pelo OIDC não tem senha, nem mesmo a vazia
+	for _, password := range []string{"", "rafa2024x"} {
+		_, err := service.Authenticate(context.Background(), "rafael@gmail.com", password, "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+}
+
+func TestDummyHashFollowsPasswordCost(t *testing.T) {
+	t.Cleanup(func() { entity.ConfigurePasswords(entity.DefaultPasswordPolicy, bcrypt.DefaultCost) })
+
+	for _, cost := range []int{bcrypt.MinCost + 1, bcrypt.MinCost} {
+		assert.NoError(t, entity.ConfigurePasswords(entity.DefaultPasswordPolicy, cost))
+
+		hashCost, err := bcrypt.Cost(dummyHash())
+		assert.NoError(t, err)
+		assert.Equal(t, cost, hashCost)
+	}
+}
+
+func TestAuthenticateLocksEmail(t *testing.T) {
+	service := setupLoginService(t)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, service.Users.Create(user))
+
+	service.Authenticate(context.Background(), "rafael@gmail.com", "errada", "10.0.0.1")
+	service.Authenticate(context.Background(), "rafael@gmail.com", "errada", "10.0.0.2")
+
+	// Nem a senha certa, de outro IP, passa durante o bloqueio
+	_, err := service.Authenticate(context.Background(), "rafael@gmail.com", "rafa2024x", "10.0.0.3")
+
+	var locked *LockedError
+	assert.True(t, errors.As(err, &locked))
+	assert.Equal(t, time.Second, locked.RetryAfter)
+}
+
+func TestAuthenticateLocksIP(t *testing.T) {
+	service := setupLoginService(t)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, service.Users.Create(user))
+
+	for _, email := range []string{"a@gmail.com", "b@gmail.com", "c@gmail.com"} {
+		service.Authenticate(context.Background(), email, "errada", "10.0.0.1")
+	}
+
+	var locked *LockedError
+	_, err := service.Authenticate(context.Background(), "rafael@gmail.com", "rafa2024x", "10.0.0.1")
+	assert.True(t, errors.As(err, &locked))
+
+	_, err = service.Authenticate(context.Background(), "rafael@gmail.com", "rafa2024x", "10.0.0.2")
+	assert.NoError(t, err)
+}
+
+func TestAuthenticateRehashesWeakerHashes(t *testing.T) {
+	t.Cleanup(func() { entity.ConfigurePasswords(entity.DefaultPasswordPolicy, bcrypt.DefaultCost) })
+
+	service := setupLoginService(t)
+	assert.NoError(t, entity.ConfigurePasswords(entity.DefaultPasswordPolicy, bcrypt.MinCost))
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, service.Users.Create(user))
+
+	assert.NoError(t, entity.ConfigurePasswords(entity.DefaultPasswordPolicy, bcrypt.MinCost+1))
+
+	// Senha errada não muda o hash
+	service.Authenticate(context.Background(), "rafael@gmail.com", "errada", "10.0.0.1")
+	found, _ := service.Users.FindByEmail("rafael@gmail.com")
+	assert.True(t, found.NeedsRehash())
+
+	_, err := service.Authenticate(context.Background(), "rafael@gmail.com", "rafa2024x", "10.0.0.1")
+	assert.NoError(t, err)
+
+	found, _ = service.Users.FindByEmail("rafael@gmail.com")
+	assert.False(t, found.NeedsRehash())
+	assert.True(t, found.ValidatePassword("rafa2024x"))
+}
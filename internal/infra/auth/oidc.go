@@ -0,0 +1,594 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+)
+
+var (
+	ErrUnknownProvider      = errors.New("provedor OIDC desconhecido")
+	ErrInvalidOIDCFlow      = errors.New("login OIDC inválido ou expirado")
+	ErrOIDCLoginFailed      = errors.New("login OIDC recusado")
+	ErrOIDCEmailNotVerified = errors.New("o provedor não confirmou o e-mail")
+)
+
+// Tempo que o usuário tem para concluir o login no provedor
+const OIDCFlowTTL = 10 * time.Minute
+
+// Algoritmos aceitos no ID token; HMAC e "none" ficam de fora
+var oidcAlgorithms = map[jwa.SignatureAlgorithm]bool{
+	jwa.RS256: true, jwa.RS384: true, jwa.RS512: true,
+	jwa.PS256: true, jwa.PS384: true, jwa.PS512: true,
+	jwa.ES256: true, jwa.ES384: true, jwa.ES512: true,
+	jwa.EdDSA: true,
+}
+
+// OIDCProvider fala com um provedor pelo fluxo authorization code com PKCE.
+// Os endpoints vêm do documento de discovery do Issuer, buscado no primeiro uso.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	Client       *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      jwk.Set
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDTokenClaims são os dados do ID token usados para achar ou criar o usuário
+type IDTokenClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+func NewOIDCProvider(name, issuer, clientID, clientSecret, redirectURL string, scopes []string) *OIDCProvider {
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &OIDCProvider{
+		Name:         name,
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+
+	if err := p.getJSON(ctx, p.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("discovery de %s: %w", p.Name, err)
+	}
+
+	// O issuer anunciado precisa ser exatamente o configurado
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("discovery de %s: issuer %q diferente do configurado", p.Name, discovery.Issuer)
+	}
+
+	p.discovery = &discovery
+
+	return p.discovery, nil
+}
+
+// AuthCodeURL monta a URL para onde o usuário é enviado para entrar no provedor
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	discovery, err := p.discover(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	link, err := url.Parse(discovery.AuthorizationEndpoint)
+
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+
+	query := link.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", p.RedirectURL)
+	query.Set("scope", strings.Join(p.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
+
+// Exchange troca o code pelo ID token, provando com o verifier do PKCE que
+// quem troca é quem iniciou o login
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	discovery, err := p.discover(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"code_verifier": {verifier},
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return "", err
+	}
+
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", "application/json")
+
+	if p.ClientSecret != "" {
+		r.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := p.Client.Do(r)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("resposta do token endpoint: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint respondeu %d: %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+
+	if body.IDToken == "" {
+		return "", errors.New("token endpoint não devolveu id_token")
+	}
+
+	return body.IDToken, nil
+}
+
+// VerifyIDToken confere assinatura, iss, aud, exp e o nonce do login
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDTokenClaims, error) {
+	message, err := jws.ParseString(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(message.Signatures()) != 1 {
+		return nil, errors.New("ID token deve ter exatamente uma assinatura")
+	}
+
+	headers := message.Signatures()[0].ProtectedHeaders()
+
+	if !oidcAlgorithms[headers.Algorithm()] {
+		return nil, fmt.Errorf("algoritmo %q não aceito no ID token", headers.Algorithm())
+	}
+
+	key, err := p.signingKey(ctx, headers.KeyID())
+
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseString(raw,
+		jwt.WithVerify(headers.Algorithm(), key),
+		jwt.WithValidate(true),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	claimedNonce, _ := token.Get("nonce")
+
+	if value, _ := claimedNonce.(string); subtle.ConstantTimeCompare([]byte(value), []byte(nonce)) != 1 {
+		return nil, errors.New("nonce do ID token não confere")
+	}
+
+	claims := &IDTokenClaims{Subject: token.Subject()}
+
+	if email, ok := token.Get("email"); ok {
+		claims.Email, _ = email.(string)
+	}
+
+	if name, ok := token.Get("name"); ok {
+		claims.Name, _ = name.(string)
+	}
+
+	// Alguns provedores mandam o booleano como string
+	if verified, ok := token.Get("email_verified"); ok {
+		switch value := verified.(type) {
+		case bool:
+			claims.EmailVerified = value
+		case string:
+			claims.EmailVerified = value == "true"
+		}
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("ID token sem sub")
+	}
+
+	return claims, nil
+}
+
+// signingKey busca o JWKS do provedor de novo quando o kid não é conhecido,
+// o que acontece logo depois de uma rotação de chaves
+func (p *OIDCProvider) signingKey(ctx context.Context, kid string) (interface{}, error) {
+	discovery, err := p.discover(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if p.keys == nil || attempt > 0 {
+			keys, err := jwk.Fetch(ctx, discovery.JWKSURI, jwk.WithHTTPClient(p.Client))
+
+			if err != nil {
+				return nil, fmt.Errorf("JWKS de %s: %w", p.Name, err)
+			}
+
+			p.keys = keys
+		}
+
+		if key, ok := lookupKey(p.keys, kid); ok {
+			var raw interface{}
+
+			if err := key.Raw(&raw); err != nil {
+				return nil, err
+			}
+
+			return raw, nil
+		}
+	}
+
+	return nil, fmt.Errorf("chave %q não encontrada no JWKS de %s", kid, p.Name)
+}
+
+// lookupKey aceita token sem kid apenas quando o provedor publica uma única chave
+func lookupKey(keys jwk.Set, kid string) (jwk.Key, bool) {
+	if kid == "" {
+		if keys.Len() != 1 {
+			return nil, false
+		}
+
+		return keys.Get(0)
+	}
+
+	return keys.LookupKeyID(kid)
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, target string, v interface{}) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.Client.Do(r)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s respondeu %d", target, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// OIDCFlow guarda entre o início do login e o callback o que o provedor não
+// pode ver: o verifier do PKCE e os valores que o callback precisa repetir
+type OIDCFlow struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Exp      int64  `json:"exp"`
+}
+
+// OIDCFlowSigner sela o OIDCFlow em um cookie assinado (HMAC-SHA256), então
+// qualquer instância do servidor consegue concluir o login
+type OIDCFlowSigner struct {
+	Key []byte
+}
+
+func NewOIDCFlowSigner(secret []byte) *OIDCFlowSigner {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("oidc-flow"))
+
+	return &OIDCFlowSigner{Key: mac.Sum(nil)}
+}
+
+func (s *OIDCFlowSigner) Seal(flow *OIDCFlow) (string, error) {
+	payload, err := json.Marshal(flow)
+
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + s.signature(encoded), nil
+}
+
+func (s *OIDCFlowSigner) Open(sealed string) (*OIDCFlow, error) {
+	encoded, signature, found := strings.Cut(sealed, ".")
+
+	if !found || !hmac.Equal([]byte(signature), []byte(s.signature(encoded))) {
+		return nil, ErrInvalidOIDCFlow
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+
+	if err != nil {
+		return nil, ErrInvalidOIDCFlow
+	}
+
+	var flow OIDCFlow
+
+	if err := json.Unmarshal(payload, &flow); err != nil || time.Now().Unix() > flow.Exp {
+		return nil, ErrInvalidOIDCFlow
+	}
+
+	return &flow, nil
+}
+
+func (s *OIDCFlowSigner) signature(encoded string) string {
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write([]byte(encoded))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// OIDCService conduz o login externo e liga a identidade do provedor a um usuário
+type OIDCService struct {
+	Providers     map[string]*OIDCProvider
+	Users         database.UserInterface
+	Identities    database.UserIdentityInterface
+	RefreshTokens database.RefreshTokenInterface
+	APIKeys       database.APIKeyInterface
+	Flows         *OIDCFlowSigner
+}
+
+func NewOIDCService(providers []*OIDCProvider, users database.UserInterface, identities database.UserIdentityInterface, refreshTokens database.RefreshTokenInterface, apiKeys database.APIKeyInterface, flows *OIDCFlowSigner) *OIDCService {
+	byName := make(map[string]*OIDCProvider, len(providers))
+
+	for _, provider := range providers {
+		byName[provider.Name] = provider
+	}
+
+	return &OIDCService{
+		Providers:     byName,
+		Users:         users,
+		Identities:    identities,
+		RefreshTokens: refreshTokens,
+		APIKeys:       apiKeys,
+		Flows:         flows,
+	}
+}
+
+func (s *OIDCService) Provider(name string) (*OIDCProvider, error) {
+	provider, ok := s.Providers[name]
+
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	return provider, nil
+}
+
+// Begin devolve a URL do provedor e o fluxo selado que volta no callback
+func (s *OIDCService) Begin(ctx context.Context, providerName string) (authURL, sealed string, err error) {
+	provider, err := s.Provider(providerName)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	flow := &OIDCFlow{Provider: provider.Name, Exp: time.Now().Add(OIDCFlowTTL).Unix()}
+
+	for _, value := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		if *value, err = randomValue(); err != nil {
+			return "", "", err
+		}
+	}
+
+	authURL, err = provider.AuthCodeURL(ctx, flow.State, flow.Nonce, flow.Verifier)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	sealed, err = s.Flows.Seal(flow)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, sealed, nil
+}
+
+// Complete valida o callback, troca o code e devolve o usuário ligado à identidade
+func (s *OIDCService) Complete(ctx context.Context, providerName, sealed, state, code string) (*entity.User, error) {
+	provider, err := s.Provider(providerName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	flow, err := s.Flows.Open(sealed)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if flow.Provider != provider.Name || subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		return nil, ErrInvalidOIDCFlow
+	}
+
+	raw, err := provider.Exchange(ctx, code, flow.Verifier)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, raw, flow.Nonce)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	return s.link(ctx, provider.Name, claims)
+}
+
+// link procura a identidade já vinculada e, na primeira vez, o usuário com o
+// mesmo e-mail, criando-o se preciso. Só e-mails confirmados pelo provedor
+// servem para vincular.
+func (s *OIDCService) link(ctx context.Context, provider string, claims *IDTokenClaims) (*entity.User, error) {
+	users := s.Users.WithContext(ctx)
+	identities := s.Identities.WithContext(ctx)
+	identity, err := identities.FindBySubject(provider, claims.Subject)
+
+	if err == nil {
+		return users.FindById(identity.UserId.String())
+	}
+
+	if !errors.Is(err, database.ErrNotFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := users.FindByEmail(claims.Email)
+
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		user, err = s.createUser(ctx, claims)
+	case err == nil && !user.IsVerified():
+		err = s.claimUnverified(ctx, user)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := identities.Create(entity.NewUserIdentity(user.Id, provider, claims.Subject, claims.Email)); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *OIDCService) createUser(ctx context.Context, claims *IDTokenClaims) (*entity.User, error) {
+	name := claims.Name
+
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	user := entity.NewExternalUser(name, claims.Email)
+	user.MarkVerified()
+
+	if err := s.Users.WithContext(ctx).Create(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// claimUnverified entrega ao dono do e-mail uma conta que ninguém confirmou.
+// A senha, as sessões e as API keys são descartadas porque quem cadastrou a
+// conta pode ter sido outra pessoa tentando tomá-la antes do dono.
+func (s *OIDCService) claimUnverified(ctx context.Context, user *entity.User) error {
+	user.ClearPassword()
+	user.MarkVerified()
+
+	if err := s.Users.WithContext(ctx).Update(user); err != nil {
+		return err
+	}
+
+	if err := s.RefreshTokens.WithContext(ctx).RevokeUser(user.Id); err != nil {
+		return err
+	}
+
+	return s.APIKeys.WithContext(ctx).RevokeUser(user.Id)
+}
+
+func randomValue() (string, error) {
+	buf := make([]byte, 32)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth/oidctest"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupOIDCService(t *testing.T) (*OIDCService, *oidctest.Provider) {
+	stub, err := oidctest.NewProvider("api-client", "s3cr&t")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(stub.Close)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&entity.User{}, &entity.UserIdentity{}, &entity.RefreshToken{}, &entity.APIKey{}); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := NewOIDCProvider("stub", stub.Issuer(), "api-client", "s3cr&t", "http://localhost:8080/users/oidc/stub/callback", nil)
+
+	return NewOIDCService(
+		[]*OIDCProvider{provider},
+		database.NewUser(db),
+		database.NewUserIdentity(db),
+		database.NewRefreshToken(db),
+		database.NewAPIKey(db),
+		NewOIDCFlowSigner([]byte("secret")),
+	), stub
+}
+
+// login percorre o fluxo inteiro: início, aprovação no provedor e callback
+func login(t *testing.T, service *OIDCService, stub *oidctest.Provider, claims map[string]interface{}) (*entity.User, error) {
+	t.Helper()
+	authURL, sealed, err := service.Begin(context.Background(), "stub")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callback, err := stub.Authorize(authURL, claims)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, _ := url.Parse(callback)
+
+	return service.Complete(context.Background(), "stub", sealed, query.Query().Get("state"), query.Query().Get("code"))
+}
+
+func TestOIDCBeginUsesPKCE(t *testing.T) {
+	service, stub := setupOIDCService(t)
+	authURL, sealed, err := service.Begin(context.Background(), "stub")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(authURL, stub.Issuer()+"/authorize?"))
+
+	link, _ := url.Parse(authURL)
+	query := link.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+
+	flow, err := service.Flows.Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, flow.State, query.Get("state"))
+	assert.Equal(t, flow.Nonce, query.Get("nonce"))
+	assert.NotEqual(t, flow.Verifier, query.Get("code_challenge"))
+	assert.NotContains(t, authURL, flow.Verifier)
+
+	_, _, err = service.Begin(context.Background(), "outro")
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+}
+
+func TestOIDCCreatesAndLinksUser(t *testing.T) {
+	service, stub := setupOIDCService(t)
+	claims := map[string]interface{}{"sub": "42", "email": "Rafael@Gmail.com", "email_verified": true, "name": "Rafael"}
+
+	user, err := login(t, service, stub, claims)
+	assert.NoError(t, err)
+	assert.Equal(t, "rafael@gmail.com", user.Email)
+	assert.Equal(t, "Rafael", user.Name)
+	assert.True(t, user.IsVerified())
+	assert.False(t, user.HasPassword())
+
+	// O vínculo pelo sub continua valendo mesmo com outro e-mail no provedor
+	claims["email"] = "novo@gmail.com"
+	again, err := login(t, service, stub, claims)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id, again.Id)
+}
+
+func TestOIDCLinksExistingAccountByEmail(t *testing.T) {
+	service, stub := setupOIDCService(t)
+	local, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	local.MarkVerified()
+	assert.NoError(t, service.Users.Create(local))
+
+	user, err := login(t, service, stub, map[string]interface{}{"email": "rafael@gmail.com", "email_verified": "true"})
+	assert.NoError(t, err)
+	assert.Equal(t, local.Id, user.Id)
+	assert.True(t, user.ValidatePassword("rafa2024x"))
+}
+
+func TestOIDCClaimsUnverifiedAccount(t *testing.T) {
+	service, stub := setupOIDCService(t)
+	squatter, _ := entity.NewUser("Outro", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, service.Users.Create(squatter))
+
+	user, err := login(t, service, stub, map[string]interface{}{"email": "rafael@gmail.com", "email_verified": true})
+	assert.NoError(t, err)
+	assert.Equal(t, squatter.Id, user.Id)
+
+	found, _ := service.Users.FindById(user.Id.String())
+	assert.True(t, found.IsVerified())
+	assert.False(t, found.ValidatePassword("rafa2024x"))
+}
+
+func TestOIDCClaimRevokesAPIKeys(t *testing.T) {
+	service, stub := setupOIDCService(t)
+	squatter, _ := entity.NewUser("Outro", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, service.Users.Create(squatter))
+
+	// Possível com EMAIL_VERIFICATION_REQUIRED=false
+	apiKeys := NewAPIKeyService(service.APIKeys, service.Users)
+	_, plain, err := apiKeys.Create(context.Background(), squatter, "antes do dono")
+	assert.NoError(t, err)
+
+	_, err = apiKeys.Authenticate(context.Background(), plain)
+	assert.NoError(t, err)
+
+	_, err = login(t, service, stub, map[string]interface{}{"email": "rafael@gmail.com", "email_verified": true})
+	assert.NoError(t, err)
+
+	_, err = apiKeys.Authenticate(context.Background(), plain)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestOIDCRequiresVerifiedEmail(t *testing.T) {
+	service, stub := setupOIDCService(t)
+
+	_, err := login(t, service, stub, map[string]interface{}{"email": "rafael@gmail.com", "email_verified": false})
+	assert.ErrorIs(t, err, ErrOIDCEmailNotVerified)
+
+	_, err = login(t, service, stub, map[string]interface{}{"email_verified": true})
+	assert.ErrorIs(t, err, ErrOIDCEmailNotVerified)
+}
+
+func TestOIDCRejectsInvalidIDTokens(t *testing.T) {
+	service, stub := setupOIDCService(t)
+	base := map[string]interface{}{"email": "rafael@gmail.com", "email_verified": true}
+
+	for name, override := range map[string]map[string]interface{}{
+		"nonce":    {"nonce": "outro"},
+		"issuer":   {"iss": "https://outro.example.com"},
+		"audience": {"aud": "outro-client"},
+		"expired":  {"exp": time.Now().Add(-time.Minute).Unix()},
+	} {
+		claims := map[string]interface{}{}
+
+		for key, value := range base {
+			claims[key] = value
+		}
+
+		for key, value := range override {
+			claims[key] = value
+		}
+
+		_, err := login(t, service, stub, claims)
+		assert.ErrorIs(t, err, ErrOIDCLoginFailed, name)
+	}
+}
+
+func TestOIDCRejectsTamperedFlow(t *testing.T) {
+	service, stub := setupOIDCService(t)
+	authURL, sealed, _ := service.Begin(context.Background(), "stub")
+	callback, _ := stub.Authorize(authURL, nil)
+	query, _ := url.Parse(callback)
+	code := query.Query().Get("code")
+
+	// State de outro login (CSRF)
+	_, err := service.Complete(context.Background(), "stub", sealed, "outro-state", code)
+	assert.ErrorIs(t, err, ErrInvalidOIDCFlow)
+
+	// Cookie alterado
+	_, err = service.Complete(context.Background(), "stub", sealed+"x", query.Query().Get("state"), code)
+	assert.ErrorIs(t, err, ErrInvalidOIDCFlow)
+
+	// Fluxo de outro verifier: o provedor recusa o code
+	_, other, _ := service.Begin(context.Background(), "stub")
+	flow, _ := service.Flows.Open(other)
+	flow.State = query.Query().Get("state")
+	forged, _ := service.Flows.Seal(flow)
+	_, err = service.Complete(context.Background(), "stub", forged, flow.State, code)
+	assert.ErrorIs(t, err, ErrOIDCLoginFailed)
+
+	expired, _ := service.Flows.Seal(&OIDCFlow{Provider: "stub", State: "s", Exp: time.Now().Add(-time.Second).Unix()})
+	_, err = service.Complete(context.Background(), "stub", expired, "s", code)
+	assert.ErrorIs(t, err, ErrInvalidOIDCFlow)
+}
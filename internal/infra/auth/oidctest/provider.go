@@ -0,0 +1,213 @@
+// Package oidctest sobe um provedor OIDC mínimo com httptest para testar o
+// login externo sem depender de um provedor real.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+const keyId = "stub-key"
+
+// Provider implementa discovery, JWKS e token endpoint. A tela de login é
+// trocada por Authorize, que aprova o pedido como se o usuário tivesse entrado.
+type Provider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+type grant struct {
+	challenge   string
+	redirectURI string
+	claims      map[string]interface{}
+}
+
+func NewProvider(clientID, clientSecret string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		grants:       map[string]grant{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+
+	return p, nil
+}
+
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+func (p *Provider) Close() {
+	p.Server.Close()
+}
+
+// Authorize valida a URL de autorização e devolve a URL de callback com code
+// e state. As claims informadas vão para o ID token e sobrescrevem as padrão.
+func (p *Provider) Authorize(authURL string, claims map[string]interface{}) (string, error) {
+	link, err := url.Parse(authURL)
+
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+
+	switch {
+	case query.Get("response_type") != "code":
+		return "", errors.New("response_type deve ser code")
+	case query.Get("client_id") != p.ClientID:
+		return "", errors.New("client_id desconhecido")
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		return "", errors.New("PKCE S256 obrigatório")
+	case !strings.Contains(" "+query.Get("scope")+" ", " openid "):
+		return "", errors.New("scope openid obrigatório")
+	}
+
+	idClaims := map[string]interface{}{
+		"iss":   p.Issuer(),
+		"aud":   p.ClientID,
+		"sub":   "stub-user",
+		"nonce": query.Get("nonce"),
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}
+
+	for name, value := range claims {
+		idClaims[name] = value
+	}
+
+	buf := make([]byte, 16)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	code := base64.RawURLEncoding.EncodeToString(buf)
+
+	p.mu.Lock()
+	p.grants[code] = grant{challenge: query.Get("code_challenge"), redirectURI: query.Get("redirect_uri"), claims: idClaims}
+	p.mu.Unlock()
+
+	callback, err := url.Parse(query.Get("redirect_uri"))
+
+	if err != nil {
+		return "", err
+	}
+
+	values := callback.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	callback.RawQuery = values.Encode()
+
+	return callback.String(), nil
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 p.Issuer(),
+		"authorization_endpoint": p.Issuer() + "/authorize",
+		"token_endpoint":         p.Issuer() + "/token",
+		"jwks_uri":               p.Issuer() + "/jwks",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	key, _ := jwk.New(&p.key.PublicKey)
+	key.Set(jwk.KeyIDKey, keyId)
+	key.Set(jwk.AlgorithmKey, jwa.RS256)
+
+	set := jwk.NewSet()
+	set.Add(key)
+	json.NewEncoder(w).Encode(set)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	// O client_id e o segredo chegam form-encoded no Basic (RFC 6749, 2.3.1)
+	clientID, secret, _ := r.BasicAuth()
+	clientID, _ = url.QueryUnescape(clientID)
+	secret, _ = url.QueryUnescape(secret)
+
+	if clientID != p.ClientID || secret != p.ClientSecret {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	p.mu.Lock()
+	code := r.PostFormValue("code")
+	g, ok := p.grants[code]
+	delete(p.grants, code)
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+
+	if !ok || g.redirectURI != r.PostFormValue("redirect_uri") || g.challenge != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	token := jwt.New()
+
+	for name, value := range g.claims {
+		token.Set(name, value)
+	}
+
+	headers := jws.NewHeaders()
+	headers.Set(jws.KeyIDKey, keyId)
+	signed, err := jwt.Sign(token, jwa.RS256, p.key, jwt.WithHeaders(headers))
+
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "stub-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     string(signed),
+	})
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/mail"
+)
+
+var (
+	ErrWrongPassword     = errors.New("senha atual incorreta")
+	ErrInvalidResetToken = errors.New("token de redefinição inválido ou expirado")
+)
+
+// PasswordService troca a senha de quem está logado e conduz a redefinição
+// por e-mail. Nos dois casos todas as sessões do usuário são encerradas.
+type PasswordService struct {
+	Users         database.UserInterface
+	ResetTokens   database.PasswordResetTokenInterface
+	RefreshTokens database.RefreshTokenInterface
+	Mailer        mail.Mailer
+	From          string
+	ResetTTL      time.Duration
+	// ResetURL recebe o token no parâmetro "token"; vazio envia apenas o token
+	ResetURL string
+	// Opcional; sem ele os pedidos de redefinição não têm limite
+	Limits *EmailLimits
+
+	queue mailQueue
+}
+
+func NewPasswordService(users database.UserInterface, resetTokens database.PasswordResetTokenInterface, refreshTokens database.RefreshTokenInterface, mailer mail.Mailer, from string, resetTTL time.Duration, resetURL string) *PasswordService {
+	return &PasswordService{
+		Users:         users,
+		ResetTokens:   resetTokens,
+		RefreshTokens: refreshTokens,
+		Mailer:        mailer,
+		From:          from,
+		ResetTTL:      resetTTL,
+		ResetURL:      resetURL,
+	}
+}
+
+func (s *PasswordService) Change(ctx context.Context, user *entity.User, current, password string) error {
+	if !user.ValidatePassword(current) {
+		return ErrWrongPassword
+	}
+
+	return s.setPassword(ctx, user, password)
+}
+
+// RequestReset envia o token por e-mail em segundo plano. Um e-mail
+// desconhecido não é erro e a resposta não espera a consulta, para que nem o
+// conteúdo nem o tempo revelem quais contas existem. Devolve *LockedError
+// quando o e-mail ou o IP passou do limite de pedidos.
+func (s *PasswordService) RequestReset(ctx context.Context, email, ip string) error {
+	email = entity.NormalizeEmail(email)
+
+	if err := s.Limits.Allow(email, ip); err != nil {
+		return err
+	}
+
+	s.queue.run(ctx, "enviando redefinição de senha", func(ctx context.Context) error {
+		return s.sendReset(ctx, email)
+	})
+
+	return nil
+}
+
+// Wait espera os envios pedidos em RequestReset
+func (s *PasswordService) Wait() {
+	s.queue.wait()
+}
+
+func (s *PasswordService) sendReset(ctx context.Context, email string) error {
+	user, err := s.Users.WithContext(ctx).FindByEmail(email)
+
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	token, plain, err := entity.NewPasswordResetToken(user.Id, s.ResetTTL)
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.ResetTokens.WithContext(ctx).Create(token); err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(mail.Message{
+		From:    s.From,
+		To:      user.Email,
+		Subject: "Redefinição de senha",
+		Body:    s.resetBody(plain),
+	})
+}
+
+// Reset troca a senha usando o token recebido por e-mail, que só vale uma vez
+func (s *PasswordService) Reset(ctx context.Context, plain, password string) error {
+	resetTokens := s.ResetTokens.WithContext(ctx)
+	token, err := resetTokens.FindByHash(entity.HashToken(plain))
+
+	if errors.Is(err, database.ErrNotFound) {
+		return ErrInvalidResetToken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if token.IsUsed() || token.IsExpired() {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.Users.WithContext(ctx).FindById(token.UserId.String())
+
+	if errors.Is(err, database.ErrNotFound) {
+		return ErrInvalidResetToken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// A política é conferida antes de gastar o token, para que o usuário
+	// possa tentar outra senha com o mesmo link
+	if err := user.ChangePassword(password); err != nil {
+		return err
+	}
+
+	err = resetTokens.Use(token)
+
+	if errors.Is(err, database.ErrTokenAlreadyUsed) {
+		return ErrInvalidResetToken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return s.savePassword(ctx, user)
+}
+
+func (s *PasswordService) setPassword(ctx context.Context, user *entity.User, password string) error {
+	if err := user.ChangePassword(password); err != nil {
+		return err
+	}
+
+	return s.savePassword(ctx, user)
+}
+
+// savePassword grava o novo hash e encerra as sessões abertas com a senha antiga
+func (s *PasswordService) savePassword(ctx context.Context, user *entity.User) error {
+	if err := s.Users.WithContext(ctx).Update(user); err != nil {
+		return err
+	}
+
+	return s.RefreshTokens.WithContext(ctx).RevokeUser(user.Id)
+}
+
+func (s *PasswordService) resetBody(token string) string {
+	body := "Use o token abaixo em POST /users/password/reset/confirm para escolher uma nova senha.\n\n" + token
+
+	if link, err := url.Parse(s.ResetURL); s.ResetURL != "" && err == nil {
+		query := link.Query()
+		query.Set("token", token)
+		link.RawQuery = query.Encode()
+		body = "Acesse o link abaixo para escolher uma nova senha.\n\n" + link.String()
+	}
+
+	return fmt.Sprintf("%s\n\nO token expira em %s e só pode ser usado uma vez.\n", body, s.ResetTTL)
+}
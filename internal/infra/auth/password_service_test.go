@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/mail"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type fakeMailer struct {
+	sent []mail.Message
+}
+
+func (m *fakeMailer) Send(msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func setupPasswordService(t *testing.T) (*PasswordService, *TokenService, *fakeMailer) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&entity.User{}, &entity.RefreshToken{}, &entity.RevokedToken{}, &entity.PasswordResetToken{}); err != nil {
+		t.Fatal(err)
+	}
+
+	mailer := &fakeMailer{}
+	tokens := NewTokenService(nil, time.Minute, time.Hour, "", "", database.NewUser(db), database.NewRefreshToken(db), database.NewRevokedToken(db))
+	passwords := NewPasswordService(
+		tokens.Users,
+		database.NewPasswordResetToken(db),
+		tokens.RefreshTokens,
+		mailer,
+		"api@localhost",
+		time.Hour,
+		"http://localhost:3000/reset?lang=pt",
+	)
+
+	return passwords, tokens, mailer
+}
+
+// resetToken extrai o token do link enviado por e-mail
+func resetToken(t *testing.T, msg mail.Message) string {
+	_, after, found := strings.Cut(msg.Body, "token=")
+
+	if !found {
+		t.Fatalf("e-mail sem token: %s", msg.Body)
+	}
+
+	return strings.Fields(after)[0]
+}
+
+func TestPasswordChange(t *testing.T) {
+	passwords, tokens, _ := setupPasswordService(t)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, tokens.Users.Create(user))
+
+	refresh, _, _ := entity.NewRefreshToken(user.Id, user.Id, time.Hour)
+	assert.NoError(t, tokens.RefreshTokens.Create(refresh))
+
+	assert.ErrorIs(t, passwords.Change(context.Background(), user, "errada", "novasenha1"), ErrWrongPassword)
+	assert.NoError(t, passwords.Change(context.Background(), user, "rafa2024x", "novasenha1"))
+
+	found, _ := tokens.Users.FindById(user.Id.String())
+	assert.True(t, found.ValidatePassword("novasenha1"))
+
+	// As sessões abertas com a senha antiga são encerradas
+	session, _ := tokens.RefreshTokens.FindByHash(refresh.TokenHash)
+	assert.True(t, session.IsRevoked())
+}
+
+func TestPasswordReset(t *testing.T) {
+	passwords, tokens, mailer := setupPasswordService(t)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, tokens.Users.Create(user))
+
+	assert.NoError(t, passwords.RequestReset(context.Background(), "Rafael@Gmail.com", "10.0.0.1"))
+	passwords.Wait()
+	assert.Len(t, mailer.sent, 1)
+	assert.Equal(t, "rafael@gmail.com", mailer.sent[0].To)
+	assert.Equal(t, "api@localhost", mailer.sent[0].From)
+	assert.Contains(t, mailer.sent[0].Body, "http://localhost:3000/reset?lang=pt&token=")
+
+	plain := resetToken(t, mailer.sent[0])
+	assert.NoError(t, passwords.Reset(context.Background(), plain, "novasenha1"))
+
+	found, _ := tokens.Users.FindById(user.Id.String())
+	assert.True(t, found.ValidatePassword("novasenha1"))
+
+	// O token só vale uma vez
+	assert.ErrorIs(t, passwords.Reset(context.Background(), plain, "outrasenha1"), ErrInvalidResetToken)
+	assert.ErrorIs(t, passwords.Reset(context.Background(), "inexistente", "outrasenha1"), ErrInvalidResetToken)
+}
+
+func TestPasswordResetUnknownEmail(t *testing.T) {
+	passwords, _, mailer := setupPasswordService(t)
+
+	assert.NoError(t, passwords.RequestReset(context.Background(), "ninguem@gmail.com", "10.0.0.1"))
+	passwords.Wait()
+	assert.Empty(t, mailer.sent)
+}
+
+func TestPasswordResetExpired(t *testing.T) {
+	passwords, tokens, mailer := setupPasswordService(t)
+	passwords.ResetTTL = -time.Second
+	passwords.ResetURL = ""
+
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, tokens.Users.Create(user))
+	assert.NoError(t, passwords.RequestReset(context.Background(), user.Email, "10.0.0.1"))
+	passwords.Wait()
+
+	lines := strings.Split(mailer.sent[0].Body, "\n")
+	assert.ErrorIs(t, passwords.Reset(context.Background(), lines[2], "novasenha1"), ErrInvalidResetToken)
+}
+
+func TestPasswordResetLimits(t *testing.T) {
+	passwords, _, mailer := setupPasswordService(t)
+	passwords.Limits = NewEmailLimits(NewLoginLimiter(3, time.Minute, time.Hour), NewLoginLimiter(4, time.Minute, time.Hour))
+
+	for _, email := range []string{"a@gmail.com", "b@gmail.com", "c@gmail.com"} {
+		assert.NoError(t, passwords.RequestReset(context.Background(), email, "10.0.0.1"))
+	}
+
+	// O quarto pedido do mesmo IP bloqueia, para qualquer e-mail
+	assert.NoError(t, passwords.RequestReset(context.Background(), "d@gmail.com", "10.0.0.1"))
+
+	var locked *LockedError
+	assert.ErrorAs(t, passwords.RequestReset(context.Background(), "e@gmail.com", "10.0.0.1"), &locked)
+	assert.Equal(t, time.Minute, locked.RetryAfter.Round(time.Second))
+
+	// Outro IP ainda pode pedir, mas o e-mail tem a sua própria cota
+	assert.NoError(t, passwords.RequestReset(context.Background(), "A@Gmail.com", "10.0.0.2"))
+	assert.NoError(t, passwords.RequestReset(context.Background(), "a@gmail.com", "10.0.0.3"))
+	assert.ErrorAs(t, passwords.RequestReset(context.Background(), "a@gmail.com", "10.0.0.4"), &locked)
+
+	passwords.Wait()
+	assert.Empty(t, mailer.sent)
+}
+
+// blockingMailer segura o envio até release ser fechado
+type blockingMailer struct {
+	release chan struct{}
+	sent    chan mail.Message
+}
+
+func (m *blockingMailer) Send(msg mail.Message) error {
+	<-m.release
+	m.sent <- msg
+	return nil
+}
+
+func TestPasswordResetDoesNotWaitForTheEmail(t *testing.T) {
+	passwords, tokens, _ := setupPasswordService(t)
+	mailer := &blockingMailer{release: make(chan struct{}), sent: make(chan mail.Message, 1)}
+	passwords.Mailer = mailer
+
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, tokens.Users.Create(user))
+
+	// A resposta sai com o envio ainda parado, como sairia para um e-mail desconhecido
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.NoError(t, passwords.RequestReset(ctx, user.Email, "10.0.0.1"))
+	cancel()
+	assert.Empty(t, mailer.sent)
+
+	// O fim da requisição não cancela o envio
+	close(mailer.release)
+	passwords.Wait()
+	assert.Equal(t, user.Email, (<-mailer.sent).To)
+}
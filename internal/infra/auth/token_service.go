@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token inválido")
+	ErrRefreshTokenReused  = errors.New("refresh token reutilizado, sessão revogada")
+)
+
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// TokenService emite os access tokens (JWT) e controla o ciclo de vida dos refresh tokens
+type TokenService struct {
+	JWT           TokenCodec
+	AccessTTL     time.Duration
+	RefreshTTL    time.Duration
+	Issuer        string
+	Audience      string
+	Users         database.UserInterface
+	RefreshTokens database.RefreshTokenInterface
+	RevokedTokens database.RevokedTokenInterface
+	// Opcional; com ele o refresh é recusado enquanto o e-mail não for verificado
+	Verification *VerificationService
+
+	// Protege AccessTTL e RefreshTTL, que SetTTL troca com o servidor no ar
+	mu sync.RWMutex
+}
+
+func NewTokenService(jwt TokenCodec, accessTTL, refreshTTL time.Duration, issuer, audience string, users database.UserInterface, refreshTokens database.RefreshTokenInterface, revokedTokens database.RevokedTokenInterface) *TokenService {
+	return &TokenService{
+		JWT:           jwt,
+		AccessTTL:     accessTTL,
+		RefreshTTL:    refreshTTL,
+		Issuer:        issuer,
+		Audience:      audience,
+		Users:         users,
+		RefreshTokens: refreshTokens,
+		RevokedTokens: revokedTokens,
+	}
+}
+
+// SetTTL muda a validade dos próximos tokens; os já emitidos não mudam
+func (s *TokenService) SetTTL(accessTTL, refreshTTL time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.AccessTTL = accessTTL
+	s.RefreshTTL = refreshTTL
+}
+
+func (s *TokenService) ttl() (accessTTL, refreshTTL time.Duration) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.AccessTTL, s.RefreshTTL
+}
+
+// Issue inicia uma nova família de refresh tokens para o login do usuário
+func (s *TokenService) Issue(ctx context.Context, user *entity.User) (*Tokens, error) {
+	_, refreshTTL := s.ttl()
+	refresh, plain, err := entity.NewRefreshToken(user.Id, pkg.NewId(), refreshTTL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.RefreshTokens.WithContext(ctx).Create(refresh); err != nil {
+		return nil, err
+	}
+
+	return s.tokens(user, plain)
+}
+
+// Refresh troca o refresh token por um novo par. Apresentar um token já
+// trocado indica vazamento, então toda a família é revogada.
+func (s *TokenService) Refresh(ctx context.Context, plain string) (*Tokens, error) {
+	refreshTokens := s.RefreshTokens.WithContext(ctx)
+	current, err := refreshTokens.FindByHash(entity.HashToken(plain))
+
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Revogado sem substituto: logout ou família já derrubada
+	if current.IsRevoked() && current.ReplacedById == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if current.IsRevoked() {
+		if err := refreshTokens.RevokeFamily(current.FamilyId); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrRefreshTokenReused
+	}
+
+	if current.IsExpired() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// O usuário é recarregado para que o novo token reflita o papel atual
+	user, err := s.Users.WithContext(ctx).FindById(current.UserId.String())
+
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// A troca de e-mail exige nova verificação também das sessões já abertas
+	if s.Verification != nil && !s.Verification.CanLogin(user) {
+		return nil, ErrEmailNotVerified
+	}
+
+	_, refreshTTL := s.ttl()
+	next, nextPlain, err := entity.NewRefreshToken(current.UserId, current.FamilyId, refreshTTL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = refreshTokens.Rotate(current, next)
+
+	// Outra requisição trocou o mesmo token ao mesmo tempo
+	if errors.Is(err, database.ErrTokenAlreadyRevoked) {
+		if err := refreshTokens.RevokeFamily(current.FamilyId); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrRefreshTokenReused
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokens(user, nextPlain)
+}
+
+// Logout coloca o access token na denylist até expirar e, se informado, revoga
+// a família do refresh token quando ele pertence ao mesmo usuário
+func (s *TokenService) Logout(ctx context.Context, userId, jti string, expiresAt time.Time, refreshToken string) error {
+	if jti != "" {
+		if err := s.RevokedTokens.WithContext(ctx).Revoke(jti, expiresAt); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	refreshTokens := s.RefreshTokens.WithContext(ctx)
+	refresh, err := refreshTokens.FindByHash(entity.HashToken(refreshToken))
+
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if refresh.UserId.String() != userId {
+		return nil
+	}
+
+	return refreshTokens.RevokeFamily(refresh.FamilyId)
+}
+
+func (s *TokenService) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.RevokedTokens.WithContext(ctx).IsRevoked(jti)
+}
+
+func (s *TokenService) tokens(user *entity.User, refreshToken string) (*Tokens, error) {
+	now := time.Now()
+	accessTTL, _ := s.ttl()
+	claims := map[string]interface{}{
+		"sub":  user.Id.String(),
+		"role": user.Role,
+		"iat":  now.Unix(),
+		"nbf":  now.Unix(),
+		"exp":  now.Add(accessTTL).Unix(),
+		"jti":  pkg.NewId().String(),
+	}
+
+	if s.Issuer != "" {
+		claims["iss"] = s.Issuer
+	}
+
+	if s.Audience != "" {
+		claims["aud"] = s.Audience
+	}
+
+	_, accessToken, err := s.JWT.Encode(claims)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    accessTTL,
+	}, nil
+}
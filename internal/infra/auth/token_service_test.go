@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTokenService(t *testing.T) *TokenService {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&entity.User{}, &entity.RefreshToken{}, &entity.RevokedToken{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewTokenService(
+		NewHMACKeySet([]byte("secret")),
+		time.Minute,
+		time.Hour,
+		"",
+		"",
+		database.NewUser(db),
+		database.NewRefreshToken(db),
+		database.NewRevokedToken(db),
+	)
+}
+
+func createUser(t *testing.T, service *TokenService, email string) *entity.User {
+	user, _ := entity.NewUser("Rafael", email, "rafa2024x")
+
+	if err := service.Users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+func TestIssue(t *testing.T) {
+	service := setupTokenService(t)
+	user := createUser(t, service, "rafael@gmail.com")
+
+	tokens, err := service.Issue(context.Background(), user)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, time.Minute, tokens.ExpiresIn)
+
+	token, err := service.JWT.Decode(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id.String(), token.Subject())
+	assert.NotEmpty(t, token.JwtID())
+
+	role, _ := token.Get("role")
+	assert.Equal(t, entity.RoleViewer, role)
+	assert.False(t, token.IssuedAt().IsZero())
+	assert.Equal(t, token.IssuedAt(), token.NotBefore())
+	assert.Equal(t, token.IssuedAt().Add(time.Minute), token.Expiration())
+	assert.Empty(t, token.Issuer())
+	assert.Empty(t, token.Audience())
+}
+
+func TestSetTTL(t *testing.T) {
+	service := setupTokenService(t)
+	user := createUser(t, service, "rafael@gmail.com")
+	service.SetTTL(10*time.Minute, 2*time.Hour)
+
+	tokens, err := service.Issue(context.Background(), user)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, tokens.ExpiresIn)
+
+	token, _ := service.JWT.Decode(tokens.AccessToken)
+	assert.Equal(t, token.IssuedAt().Add(10*time.Minute), token.Expiration())
+
+	refresh, _ := service.RefreshTokens.FindByHash(entity.HashToken(tokens.RefreshToken))
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), refresh.ExpiresAt, time.Minute)
+}
+
+func TestIssueWithIssuerAndAudience(t *testing.T) {
+	service := setupTokenService(t)
+	service.Issuer = "https://api.example.com"
+	service.Audience = "products"
+	user := createUser(t, service, "rafael@gmail.com")
+
+	tokens, err := service.Issue(context.Background(), user)
+	assert.NoError(t, err)
+
+	token, err := service.JWT.Decode(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.example.com", token.Issuer())
+	assert.Equal(t, []string{"products"}, token.Audience())
+}
+
+func TestRefreshUsesCurrentRole(t *testing.T) {
+	service := setupTokenService(t)
+	user := createUser(t, service, "rafael@gmail.com")
+
+	tokens, err := service.Issue(context.Background(), user)
+	assert.NoError(t, err)
+
+	assert.NoError(t, user.SetRole(entity.RoleEditor))
+	assert.NoError(t, service.Users.Update(user))
+
+	tokens, err = service.Refresh(context.Background(), tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	token, err := service.JWT.Decode(tokens.AccessToken)
+	assert.NoError(t, err)
+
+	role, _ := token.Get("role")
+	assert.Equal(t, entity.RoleEditor, role)
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	service := setupTokenService(t)
+	user := createUser(t, service, "rafael@gmail.com")
+
+	first, err := service.Issue(context.Background(), user)
+	assert.NoError(t, err)
+
+	second, err := service.Refresh(context.Background(), first.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	token, err := service.JWT.Decode(second.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id.String(), token.Subject())
+
+	_, err = service.Refresh(context.Background(), "desconhecido")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	service := setupTokenService(t)
+	user := createUser(t, service, "rafael@gmail.com")
+
+	first, _ := service.Issue(context.Background(), user)
+	second, err := service.Refresh(context.Background(), first.RefreshToken)
+	assert.NoError(t, err)
+
+	// O token antigo volta a ser usado: a família inteira cai
+	_, err = service.Refresh(context.Background(), first.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	_, err = service.Refresh(context.Background(), second.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefreshExpired(t *testing.T) {
+	service := setupTokenService(t)
+	service.RefreshTTL = -time.Second
+	user := createUser(t, service, "rafael@gmail.com")
+
+	tokens, _ := service.Issue(context.Background(), user)
+	_, err := service.Refresh(context.Background(), tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestLogout(t *testing.T) {
+	service := setupTokenService(t)
+	user := createUser(t, service, "rafael@gmail.com")
+	other := createUser(t, service, "outro@gmail.com")
+
+	tokens, _ := service.Issue(context.Background(), user)
+	otherTokens, _ := service.Issue(context.Background(), other)
+	token, _ := service.JWT.Decode(tokens.AccessToken)
+
+	// O refresh token de outro usuário é ignorado
+	assert.NoError(t, service.Logout(context.Background(), user.Id.String(), token.JwtID(), token.Expiration(), otherTokens.RefreshToken))
+	_, err := service.Refresh(context.Background(), otherTokens.RefreshToken)
+	assert.NoError(t, err)
+
+	assert.NoError(t, service.Logout(context.Background(), user.Id.String(), token.JwtID(), token.Expiration(), tokens.RefreshToken))
+
+	revoked, err := service.IsRevoked(context.Background(), token.JwtID())
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = service.Refresh(context.Background(), tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefreshRequiresVerifiedEmail(t *testing.T) {
+	service := setupTokenService(t)
+	service.Verification = &VerificationService{Required: true}
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	user.MarkVerified()
+	assert.NoError(t, service.Users.Create(user))
+
+	tokens, err := service.Issue(context.Background(), user)
+	assert.NoError(t, err)
+
+	// Trocar o e-mail derruba a verificação, e com ela a renovação da sessão
+	user.ChangeEmail("novo@gmail.com")
+	assert.NoError(t, service.Users.Update(user))
+
+	_, err = service.Refresh(context.Background(), tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+
+	// O token não foi gasto e volta a valer depois da confirmação
+	user.MarkVerified()
+	assert.NoError(t, service.Users.Update(user))
+
+	_, err = service.Refresh(context.Background(), tokens.RefreshToken)
+	assert.NoError(t, err)
+}
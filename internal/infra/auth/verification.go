@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/mail"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("token de verificação inválido ou expirado")
+	ErrEmailNotVerified         = errors.New("e-mail não verificado")
+)
+
+// VerificationSigner gera tokens assinados (HMAC-SHA256) que confirmam a posse
+// de um e-mail. A chave é derivada da informada para que um token de
+// verificação nunca seja aceito como access token e vice-versa.
+type VerificationSigner struct {
+	Key []byte
+	TTL time.Duration
+}
+
+type verificationClaims struct {
+	Sub   string `json:"sub"`
+	Email string `json:"email"`
+	Exp   int64  `json:"exp"`
+}
+
+func NewVerificationSigner(secret []byte, ttl time.Duration) *VerificationSigner {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("email-verification"))
+
+	return &VerificationSigner{Key: mac.Sum(nil), TTL: ttl}
+}
+
+func (s *VerificationSigner) Sign(user *entity.User) (string, error) {
+	payload, err := json.Marshal(verificationClaims{
+		Sub:   user.Id.String(),
+		Email: user.Email,
+		Exp:   time.Now().Add(s.TTL).Unix(),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + s.signature(encoded), nil
+}
+
+// Parse devolve o usuário e o e-mail do token. O e-mail faz com que o token
+// deixe de valer se o endereço for trocado antes da confirmação.
+func (s *VerificationSigner) Parse(token string) (userId, email string, err error) {
+	encoded, signature, found := strings.Cut(token, ".")
+
+	if !found || !hmac.Equal([]byte(signature), []byte(s.signature(encoded))) {
+		return "", "", ErrInvalidVerificationToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+
+	if err != nil {
+		return "", "", ErrInvalidVerificationToken
+	}
+
+	var claims verificationClaims
+
+	if err := json.Unmarshal(payload, &claims); err != nil || time.Now().Unix() > claims.Exp {
+		return "", "", ErrInvalidVerificationToken
+	}
+
+	return claims.Sub, claims.Email, nil
+}
+
+func (s *VerificationSigner) signature(encoded string) string {
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write([]byte(encoded))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Notifier entrega o token de verificação ao dono do e-mail
+type Notifier interface {
+	NotifyVerification(user *entity.User, token string) error
+}
+
+// MailNotifier envia o link de verificação pelo mail.Mailer
+type MailNotifier struct {
+	Mailer mail.Mailer
+	From   string
+	// VerifyURL recebe o token no parâmetro "token", normalmente o GET /users/verify
+	VerifyURL string
+}
+
+func NewMailNotifier(mailer mail.Mailer, from, verifyURL string) *MailNotifier {
+	return &MailNotifier{Mailer: mailer, From: from, VerifyURL: verifyURL}
+}
+
+func (n *MailNotifier) NotifyVerification(user *entity.User, token string) error {
+	link, err := url.Parse(n.VerifyURL)
+
+	if err != nil {
+		return fmt.Errorf("EMAIL_VERIFICATION_URL inválida: %w", err)
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return n.Mailer.Send(mail.Message{
+		From:    n.From,
+		To:      user.Email,
+		Subject: "Confirme seu e-mail",
+		Body:    fmt.Sprintf("Olá, %s.\n\nAcesse o link abaixo para confirmar seu e-mail.\n\n%s\n", user.Name, link.String()),
+	})
+}
+
+// VerificationService confirma os e-mails dos usuários. Com Required falso as
+// contas podem entrar sem confirmar e nenhum e-mail é enviado.
+type VerificationService struct {
+	Users    database.UserInterface
+	Signer   *VerificationSigner
+	Notifier Notifier
+	Required bool
+	// Opcional; sem ele os reenvios não têm limite
+	Limits *EmailLimits
+
+	queue mailQueue
+}
+
+func NewVerificationService(users database.UserInterface, signer *VerificationSigner, notifier Notifier, required bool) *VerificationService {
+	return &VerificationService{
+		Users:    users,
+		Signer:   signer,
+		Notifier: notifier,
+		Required: required,
+	}
+}
+
+// Send envia o token para um usuário ainda não verificado
+func (s *VerificationService) Send(user *entity.User) error {
+	if !s.Required || user.IsVerified() {
+		return nil
+	}
+
+	token, err := s.Signer.Sign(user)
+
+	if err != nil {
+		return err
+	}
+
+	return s.Notifier.NotifyVerification(user, token)
+}
+
+// Resend reenvia o token em segundo plano. Um e-mail desconhecido não é erro
+// e a resposta não espera a consulta, para que nem o conteúdo nem o tempo
+// revelem quais contas existem. Devolve *LockedError quando o e-mail ou o IP
+// passou do limite de pedidos.
+func (s *VerificationService) Resend(ctx context.Context, email, ip string) error {
+	email = entity.NormalizeEmail(email)
+
+	if err := s.Limits.Allow(email, ip); err != nil {
+		return err
+	}
+
+	s.queue.run(ctx, "reenviando verificação", func(ctx context.Context) error {
+		return s.resend(ctx, email)
+	})
+
+	return nil
+}
+
+// Wait espera os envios pedidos em Resend
+func (s *VerificationService) Wait() {
+	s.queue.wait()
+}
+
+func (s *VerificationService) resend(ctx context.Context, email string) error {
+	user, err := s.Users.WithContext(ctx).FindByEmail(email)
+
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return s.Send(user)
+}
+
+// Verify marca o usuário do token como verificado. Confirmar de novo não é erro.
+func (s *VerificationService) Verify(ctx context.Context, token string) (*entity.User, error) {
+	userId, email, err := s.Signer.Parse(token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	users := s.Users.WithContext(ctx)
+	user, err := users.FindById(userId)
+
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Email != email {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if user.IsVerified() {
+		return user, nil
+	}
+
+	user.MarkVerified()
+
+	if err := users.Update(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CanLogin diz se a conta pode receber tokens
+func (s *VerificationService) CanLogin(user *entity.User) bool {
+	return !s.Required || user.IsVerified()
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type fakeNotifier struct {
+	tokens map[string]string
+}
+
+func (n *fakeNotifier) NotifyVerification(user *entity.User, token string) error {
+	n.tokens[user.Email] = token
+	return nil
+}
+
+func setupVerificationService(t *testing.T) (*VerificationService, *fakeNotifier) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&entity.User{}); err != nil {
+		t.Fatal(err)
+	}
+
+	notifier := &fakeNotifier{tokens: map[string]string{}}
+	signer := NewVerificationSigner([]byte("secret"), time.Hour)
+
+	return NewVerificationService(database.NewUser(db), signer, notifier, true), notifier
+}
+
+func TestVerificationSigner(t *testing.T) {
+	signer := NewVerificationSigner([]byte("secret"), time.Hour)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+
+	token, err := signer.Sign(user)
+	assert.NoError(t, err)
+
+	userId, email, err := signer.Parse(token)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id.String(), userId)
+	assert.Equal(t, user.Email, email)
+
+	// Assinado com outra chave
+	_, _, err = NewVerificationSigner([]byte("outra"), time.Hour).Parse(token)
+	assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+
+	// Payload alterado
+	encoded, signature, _ := strings.Cut(token, ".")
+	_, _, err = signer.Parse(encoded + "x." + signature)
+	assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+
+	expired, _ := NewVerificationSigner([]byte("secret"), -time.Minute).Sign(user)
+	_, _, err = signer.Parse(expired)
+	assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+}
+
+// O token de verificação não pode servir como access token assinado com o mesmo segredo
+func TestVerificationTokenIsNotAccessToken(t *testing.T) {
+	signer := NewVerificationSigner([]byte("secret"), time.Hour)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	token, _ := signer.Sign(user)
+
+	_, err := jwtauth.VerifyToken(jwtauth.New("HS256", []byte("secret"), nil), token)
+	assert.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	service, notifier := setupVerificationService(t)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, service.Users.Create(user))
+	assert.False(t, service.CanLogin(user))
+
+	assert.NoError(t, service.Send(user))
+	token := notifier.tokens["rafael@gmail.com"]
+	assert.NotEmpty(t, token)
+
+	verified, err := service.Verify(context.Background(), token)
+	assert.NoError(t, err)
+	assert.True(t, verified.IsVerified())
+	assert.True(t, service.CanLogin(verified))
+
+	found, _ := service.Users.FindById(user.Id.String())
+	assert.True(t, found.IsVerified())
+
+	// Confirmar de novo não é erro
+	_, err = service.Verify(context.Background(), token)
+	assert.NoError(t, err)
+
+	_, err = service.Verify(context.Background(), "invalido")
+	assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+}
+
+func TestVerifyAfterEmailChange(t *testing.T) {
+	service, notifier := setupVerificationService(t)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, service.Users.Create(user))
+	assert.NoError(t, service.Send(user))
+
+	user.ChangeEmail("outro@gmail.com")
+	assert.NoError(t, service.Users.Update(user))
+
+	_, err := service.Verify(context.Background(), notifier.tokens["rafael@gmail.com"])
+	assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+}
+
+func TestResend(t *testing.T) {
+	service, notifier := setupVerificationService(t)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, service.Users.Create(user))
+
+	assert.NoError(t, service.Resend(context.Background(), "ninguem@gmail.com", "10.0.0.1"))
+	service.Wait()
+	assert.Empty(t, notifier.tokens)
+
+	assert.NoError(t, service.Resend(context.Background(), "Rafael@Gmail.com", "10.0.0.1"))
+	service.Wait()
+	assert.NotEmpty(t, notifier.tokens["rafael@gmail.com"])
+}
+
+func TestVerificationNotRequired(t *testing.T) {
+	service, notifier := setupVerificationService(t)
+	service.Required = false
+
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.True(t, service.CanLogin(user))
+	assert.NoError(t, service.Send(user))
+	assert.Empty(t, notifier.tokens)
+}
+
+func TestMailNotifier(t *testing.T) {
+	mailer := &fakeMailer{}
+	notifier := NewMailNotifier(mailer, "api@localhost", "http://localhost:8080/users/verify")
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+
+	assert.NoError(t, notifier.NotifyVerification(user, "abc.def"))
+	assert.Len(t, mailer.sent, 1)
+	assert.Equal(t, "rafael@gmail.com", mailer.sent[0].To)
+	assert.Contains(t, mailer.sent[0].Body, "http://localhost:8080/users/verify?token=abc.def")
+}
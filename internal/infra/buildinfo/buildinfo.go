@@ -0,0 +1,53 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Preenchidos no build, por exemplo:
+//
+//	go build -ldflags "-X github.com/rafaelsouzaribeiro/9-API/internal/infra/buildinfo.Version=v1.4.0 \
+//	  -X github.com/rafaelsouzaribeiro/9-API/internal/infra/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/rafaelsouzaribeiro/9-API/internal/infra/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+type Info struct {
+	Version   string
+	Commit    string
+	BuildTime string
+	GoVersion string
+}
+
+// Read usa os valores do -ldflags e completa o que faltar com o que o Go grava
+// no binário (versão do módulo e dados do git), ou "unknown".
+func Read() Info {
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	for _, value := range []*string{&info.Version, &info.Commit, &info.BuildTime} {
+		if *value == "" {
+			*value = "unknown"
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,29 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadUsesLdflags(t *testing.T) {
+	Version, Commit, BuildTime = "v1.4.0", "3f408a5", "2024-01-02T15:04:05Z"
+	defer func() { Version, Commit, BuildTime = "", "", "" }()
+
+	assert.Equal(t, Info{
+		Version:   "v1.4.0",
+		Commit:    "3f408a5",
+		BuildTime: "2024-01-02T15:04:05Z",
+		GoVersion: runtime.Version(),
+	}, Read())
+}
+
+func TestReadWithoutLdflags(t *testing.T) {
+	info := Read()
+
+	assert.NotEmpty(t, info.Version)
+	assert.NotEmpty(t, info.Commit)
+	assert.NotEmpty(t, info.BuildTime)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+}
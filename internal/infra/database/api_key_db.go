@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type APIKey struct {
+	DB *gorm.DB
+}
+
+func NewAPIKey(db *gorm.DB) *APIKey {
+	return &APIKey{DB: db}
+}
+
+func (k *APIKey) WithContext(ctx context.Context) APIKeyInterface {
+	return &APIKey{DB: k.DB.WithContext(ctx)}
+}
+
+func (k *APIKey) Create(key *entity.APIKey) error {
+	return k.DB.Create(key).Error
+}
+
+func (k *APIKey) FindByHash(hash string) (*entity.APIKey, error) {
+	var key entity.APIKey
+
+	if err := k.DB.Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// FindByUser lista as chaves ainda ativas do usuário, das mais novas para as mais antigas
+func (k *APIKey) FindByUser(userId pkg.Id) ([]entity.APIKey, error) {
+	var keys []entity.APIKey
+
+	err := k.DB.Where("user_id = ? AND revoked_at IS NULL", userId).
+		Order("created_at DESC").Order("id").
+		Find(&keys).Error
+
+	return keys, err
+}
+
+// Revoke só alcança chaves do próprio usuário; revogar de novo não é erro
+func (k *APIKey) Revoke(userId pkg.Id, id string) error {
+	var key entity.APIKey
+
+	if err := k.DB.Where("id = ? AND user_id = ?", id, userId).First(&key).Error; err != nil {
+		return err
+	}
+
+	return k.DB.Model(&entity.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", key.Id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUser revoga todas as chaves ativas do usuário
+func (k *APIKey) RevokeUser(userId pkg.Id) error {
+	return k.DB.Model(&entity.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (k *APIKey) Touch(id pkg.Id, usedAt time.Time) error {
+	return k.DB.Model(&entity.APIKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyCreateAndFindByHash(t *testing.T) {
+	dataRef := &entity.APIKey{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	key, plain, _ := entity.NewAPIKey(pkg.NewId(), "importador")
+	keyDb := NewAPIKey(db)
+	assert.NoError(t, keyDb.Create(key))
+
+	found, err := keyDb.FindByHash(entity.HashToken(plain))
+	assert.NoError(t, err)
+	assert.Equal(t, key.Id, found.Id)
+	assert.Equal(t, "importador", found.Name)
+	assert.Nil(t, found.LastUsedAt)
+
+	_, err = keyDb.FindByHash(entity.HashToken("ak_outra"))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	usedAt := time.Now().Truncate(time.Second)
+	assert.NoError(t, keyDb.Touch(key.Id, usedAt))
+
+	found, _ = keyDb.FindByHash(key.KeyHash)
+	assert.True(t, usedAt.Equal(*found.LastUsedAt))
+}
+
+func TestAPIKeyFindByUserAndRevoke(t *testing.T) {
+	dataRef := &entity.APIKey{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	userId := pkg.NewId()
+	keyDb := NewAPIKey(db)
+
+	first, _, _ := entity.NewAPIKey(userId, "primeira")
+	second, _, _ := entity.NewAPIKey(userId, "segunda")
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	other, _, _ := entity.NewAPIKey(pkg.NewId(), "de outro")
+
+	for _, key := range []*entity.APIKey{first, second, other} {
+		assert.NoError(t, keyDb.Create(key))
+	}
+
+	keys, err := keyDb.FindByUser(userId)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, second.Id, keys[0].Id)
+
+	// A chave de outro usuário não pode ser revogada por este
+	assert.ErrorIs(t, keyDb.Revoke(userId, other.Id.String()), ErrNotFound)
+
+	assert.NoError(t, keyDb.Revoke(userId, first.Id.String()))
+	assert.NoError(t, keyDb.Revoke(userId, first.Id.String()))
+
+	keys, _ = keyDb.FindByUser(userId)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, second.Id, keys[0].Id)
+
+	revoked, _ := keyDb.FindByHash(first.KeyHash)
+	assert.True(t, revoked.IsRevoked())
+}
@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var ErrUnsupportedDriver = errors.New("driver de banco de dados não suportado")
+
+const (
+	DriverSqlite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMysql    = "mysql"
+)
+
+// Config descreve a conexão com o banco, montada a partir do configs.conf
+type Config struct {
+	Driver          string
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	Name            string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// NormalizeDriver aceita os apelidos mais comuns de cada driver
+func NormalizeDriver(driver string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(driver)) {
+	case "sqlite", "sqlite3":
+		return DriverSqlite, nil
+	case "postgres", "postgresql", "pgx":
+		return DriverPostgres, nil
+	case "mysql", "mariadb":
+		return DriverMysql, nil
+	}
+
+	return "", fmt.Errorf("%w: %q (use sqlite, postgres ou mysql)", ErrUnsupportedDriver, driver)
+}
+
+func DSN(cfg Config) (string, error) {
+	driver, err := NormalizeDriver(cfg.Driver)
+
+	if err != nil {
+		return "", err
+	}
+
+	switch driver {
+	case DriverSqlite:
+		// Sem nome ou ":memory:" o banco fica em memória, compartilhado entre as conexões do pool
+		if cfg.Name == "" || cfg.Name == ":memory:" {
+			return "file::memory:?cache=shared", nil
+		}
+
+		return cfg.Name, nil
+	case DriverPostgres:
+		sslMode := cfg.SSLMode
+
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(cfg.User, cfg.Password),
+			Host:     net.JoinHostPort(cfg.Host, defaultPort(cfg.Port, "5432")),
+			Path:     "/" + cfg.Name,
+			RawQuery: url.Values{"sslmode": {sslMode}, "TimeZone": {"UTC"}}.Encode(),
+		}
+
+		return dsn.String(), nil
+	default:
+		mysqlCfg := mysql.NewConfig()
+		mysqlCfg.User = cfg.User
+		mysqlCfg.Passwd = cfg.Password
+		mysqlCfg.Net = "tcp"
+		mysqlCfg.Addr = net.JoinHostPort(cfg.Host, defaultPort(cfg.Port, "3306"))
+		mysqlCfg.DBName = cfg.Name
+		mysqlCfg.ParseTime = true
+		mysqlCfg.Loc = time.UTC
+		mysqlCfg.Params = map[string]string{"charset": "utf8mb4"}
+
+		return mysqlCfg.FormatDSN(), nil
+	}
+}
+
+func NewDialector(cfg Config) (gorm.Dialector, error) {
+	dsn, err := DSN(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	driver, _ := NormalizeDriver(cfg.Driver)
+
+	switch driver {
+	case DriverSqlite:
+		return sqlite.Open(dsn), nil
+	case DriverPostgres:
+		return postgres.Open(dsn), nil
+	default:
+		return gormmysql.Open(dsn), nil
+	}
+}
+
+// Open abre a conexão e aplica as configurações do pool
+func Open(cfg Config) (*gorm.DB, error) {
+	dialector, err := NewDialector(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+
+	if err != nil {
+		return nil, fmt.Errorf("conectando ao banco %s: %w", cfg.Driver, err)
+	}
+
+	sqlDB, err := db.DB()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+
+	return db, nil
+}
+
+// Close fecha o pool de conexões aberto por Open
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.Close()
+}
+
+// Ping confere se o banco responde dentro do prazo de ctx
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+func defaultPort(port, fallback string) string {
+	if port == "" {
+		return fallback
+	}
+
+	return port
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDSNSqlite(t *testing.T) {
+	dsn, err := DSN(Config{Driver: "sqlite3", Name: "teste.db"})
+	assert.NoError(t, err)
+	assert.Equal(t, "teste.db", dsn)
+
+	dsn, err = DSN(Config{Driver: "sqlite"})
+	assert.NoError(t, err)
+	assert.Equal(t, "file::memory:?cache=shared", dsn)
+}
+
+func TestDSNPostgres(t *testing.T) {
+	dsn, err := DSN(Config{
+		Driver:   "postgres",
+		Host:     "db",
+		User:     "api",
+		Password: "p@ss word",
+		Name:     "products",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://api:p%40ss%20word@db:5432/products?TimeZone=UTC&sslmode=disable", dsn)
+}
+
+func TestDSNMysql(t *testing.T) {
+	dsn, err := DSN(Config{
+		Driver:   "mysql",
+		Host:     "db",
+		Port:     "3307",
+		User:     "root",
+		Password: "root",
+		Name:     "fullcycles",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "root:root@tcp(db:3307)/fullcycles?parseTime=true&charset=utf8mb4", dsn)
+}
+
+func TestDSNUnsupportedDriver(t *testing.T) {
+	_, err := DSN(Config{Driver: "oracle"})
+	assert.ErrorIs(t, err, ErrUnsupportedDriver)
+	assert.Contains(t, err.Error(), "oracle")
+}
+
+func TestOpenSqliteMemory(t *testing.T) {
+	db, err := Open(Config{Driver: "sqlite", MaxOpenConns: 2, MaxIdleConns: 1})
+	assert.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sqlDB.Stats().MaxOpenConnections)
+	assert.NoError(t, sqlDB.Ping())
+}
+
+func TestClose(t *testing.T) {
+	db, err := Open(Config{Driver: "sqlite"})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Exec("SELECT 1").Error)
+
+	assert.NoError(t, Close(db))
+	assert.Error(t, db.Exec("SELECT 1").Error)
+}
+
+func TestPing(t *testing.T) {
+	db, err := Open(Config{Driver: "sqlite"})
+	assert.NoError(t, err)
+	assert.NoError(t, Ping(context.Background(), db))
+
+	assert.NoError(t, Close(db))
+	assert.Error(t, Ping(context.Background(), db))
+}
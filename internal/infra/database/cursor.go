@@ -0,0 +1,85 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+)
+
+var ErrInvalidCursor = errors.New("cursor inválido")
+
+// Cursor marca a posição (campo de ordenação, id) do último item entregue na paginação por keyset
+type Cursor struct {
+	SortBy    string    `json:"s,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	Name      string    `json:"n,omitempty"`
+	Price     float64   `json:"p,omitempty"`
+	Id        string    `json:"i"`
+}
+
+func CursorFor(product entity.Product, sortBy string) *Cursor {
+	cursor := &Cursor{SortBy: sortBy, Id: product.Id.String()}
+
+	switch sortBy {
+	case SortByName:
+		cursor.Name = product.Name
+	case SortByPrice:
+		cursor.Price = product.Price
+	default:
+		cursor.SortBy = SortByCreatedAt
+		cursor.CreatedAt = product.CreatedAt
+	}
+
+	return cursor
+}
+
+// value devolve o valor do campo de ordenação usado na comparação do keyset
+func (c *Cursor) value() interface{} {
+	switch c.SortBy {
+	case SortByName:
+		return c.Name
+	case SortByPrice:
+		return c.Price
+	default:
+		return c.CreatedAt
+	}
+}
+
+// Encode gera o valor opaco enviado ao cliente
+func (c *Cursor) Encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// Cursores sem campo de ordenação são da versão que só ordenava por created_at
+	if cursor.SortBy == "" {
+		cursor.SortBy = SortByCreatedAt
+	}
+
+	if !IsValidSortBy(cursor.SortBy) || (cursor.SortBy == SortByCreatedAt && cursor.CreatedAt.IsZero()) {
+		return nil, ErrInvalidCursor
+	}
+
+	if _, err := pkg.ParseId(cursor.Id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound permite que as camadas de cima tratem registro inexistente sem depender do gorm
+var ErrNotFound = gorm.ErrRecordNotFound
+
+var ErrTokenAlreadyRevoked = errors.New("token já revogado")
+
+var ErrTokenAlreadyUsed = errors.New("token já utilizado")
+
+var ErrEmailAlreadyExists = errors.New("e-mail já cadastrado")
+
+// translateError converte os erros específicos de cada driver nos erros do gorm
+func translateError(db *gorm.DB, err error) error {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		return translator.Translate(err)
+	}
+
+	return err
+}
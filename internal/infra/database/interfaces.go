@@ -1,16 +1,70 @@
 package database
 
-import "github.com/rafaelsouzaribeiro/9-API/internal/entity"
+import (
+	"context"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+)
 
 type UserInterface interface {
 	Create(user *entity.User) error
 	FindByEmail(email string) (*entity.User, error)
+	FindById(id string) (*entity.User, error)
+	Update(user *entity.User) error
+	Delete(id string) error
+	FindAll(page, limit int) ([]entity.User, int64, error)
+	// WithContext devolve uma cópia cujas consultas usam ctx. O mesmo vale
+	// para o WithContext das outras interfaces deste arquivo.
+	WithContext(ctx context.Context) UserInterface
 }
 
 type ProductInterface interface {
 	Create(product *entity.Product) error
-	FindAll(page, limit int, sort string) ([]entity.Product, error)
+	FindAll(query ProductQuery) ([]entity.Product, int64, error)
+	FindAfter(query ProductQuery) ([]entity.Product, *Cursor, int64, error)
 	FindById(id string) (*entity.Product, error)
 	Update(product *entity.Product) error
 	Delete(id string) error
+	WithContext(ctx context.Context) ProductInterface
+}
+
+type RefreshTokenInterface interface {
+	Create(token *entity.RefreshToken) error
+	FindByHash(hash string) (*entity.RefreshToken, error)
+	Rotate(current, next *entity.RefreshToken) error
+	RevokeFamily(familyId pkg.Id) error
+	RevokeUser(userId pkg.Id) error
+	WithContext(ctx context.Context) RefreshTokenInterface
+}
+
+type PasswordResetTokenInterface interface {
+	Create(token *entity.PasswordResetToken) error
+	FindByHash(hash string) (*entity.PasswordResetToken, error)
+	Use(token *entity.PasswordResetToken) error
+	WithContext(ctx context.Context) PasswordResetTokenInterface
+}
+
+type APIKeyInterface interface {
+	Create(key *entity.APIKey) error
+	FindByHash(hash string) (*entity.APIKey, error)
+	FindByUser(userId pkg.Id) ([]entity.APIKey, error)
+	Revoke(userId pkg.Id, id string) error
+	RevokeUser(userId pkg.Id) error
+	Touch(id pkg.Id, usedAt time.Time) error
+	WithContext(ctx context.Context) APIKeyInterface
+}
+
+type UserIdentityInterface interface {
+	Create(identity *entity.UserIdentity) error
+	FindBySubject(provider, subject string) (*entity.UserIdentity, error)
+	WithContext(ctx context.Context) UserIdentityInterface
+}
+
+type RevokedTokenInterface interface {
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) (bool, error)
+	DeleteExpired() (int64, error)
+	WithContext(ctx context.Context) RevokedTokenInterface
 }
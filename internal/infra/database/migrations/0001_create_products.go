@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"gorm.io/gorm"
+)
+
+// Cópia do entity.Product no momento da migration, para que mudanças
+// futuras na entidade não alterem o que esta versão cria
+type product0001 struct {
+	Id        entity.Id `gorm:"primaryKey;size:36"`
+	Name      string    `gorm:"size:255;not null"`
+	Price     float64   `gorm:"not null"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (product0001) TableName() string {
+	return "products"
+}
+
+var createProducts = Migration{
+	Version: 1,
+	Name:    "create_products",
+	Up: func(tx *gorm.DB) error {
+		// Bancos criados pelo antigo AutoMigrate já possuem a tabela
+		if tx.Migrator().HasTable(&product0001{}) {
+			return nil
+		}
+
+		return tx.Migrator().CreateTable(&product0001{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&product0001{})
+	},
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type user0002 struct {
+	Id       entity.Id `gorm:"primaryKey;size:36"`
+	Name     string    `gorm:"size:255"`
+	Email    string    `gorm:"size:255"`
+	Password string    `gorm:"size:255"`
+}
+
+func (user0002) TableName() string {
+	return "users"
+}
+
+var createUsers = Migration{
+	Version: 2,
+	Name:    "create_users",
+	Up: func(tx *gorm.DB) error {
+		if tx.Migrator().HasTable(&user0002{}) {
+			return nil
+		}
+
+		return tx.Migrator().CreateTable(&user0002{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&user0002{})
+	},
+}
@@ -0,0 +1,15 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Índice usado pela paginação por cursor (created_at, id)
+var addProductsKeysetIndex = Migration{
+	Version: 3,
+	Name:    "add_products_keyset_index",
+	Up: func(tx *gorm.DB) error {
+		return tx.Exec("CREATE INDEX idx_products_created_at_id ON products (created_at, id)").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropIndexIfExists(tx, "products", "idx_products_created_at_id")
+	},
+}
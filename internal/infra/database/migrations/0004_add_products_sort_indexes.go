@@ -0,0 +1,23 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Índices para ordenar e paginar por nome e preço
+var addProductsSortIndexes = Migration{
+	Version: 4,
+	Name:    "add_products_sort_indexes",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Exec("CREATE INDEX idx_products_name_id ON products (name, id)").Error; err != nil {
+			return err
+		}
+
+		return tx.Exec("CREATE INDEX idx_products_price_id ON products (price, id)").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := dropIndexIfExists(tx, "products", "idx_products_price_id"); err != nil {
+			return err
+		}
+
+		return dropIndexIfExists(tx, "products", "idx_products_name_id")
+	},
+}
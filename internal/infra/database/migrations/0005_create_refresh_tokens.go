@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type refreshToken0005 struct {
+	Id           entity.Id `gorm:"primaryKey;size:36"`
+	UserId       entity.Id `gorm:"size:36;not null;index"`
+	FamilyId     entity.Id `gorm:"size:36;not null;index"`
+	TokenHash    string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"not null"`
+	RevokedAt    *time.Time
+	ReplacedById *entity.Id `gorm:"size:36"`
+}
+
+func (refreshToken0005) TableName() string {
+	return "refresh_tokens"
+}
+
+var createRefreshTokens = Migration{
+	Version: 5,
+	Name:    "create_refresh_tokens",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&refreshToken0005{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&refreshToken0005{})
+	},
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type revokedToken0006 struct {
+	Jti       string    `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (revokedToken0006) TableName() string {
+	return "revoked_tokens"
+}
+
+var createRevokedTokens = Migration{
+	Version: 6,
+	Name:    "create_revoked_tokens",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&revokedToken0006{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&revokedToken0006{})
+	},
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type user0007 struct {
+	Role string `gorm:"size:20;not null;default:viewer"`
+}
+
+func (user0007) TableName() string {
+	return "users"
+}
+
+var addUsersRole = Migration{
+	Version: 7,
+	Name:    "add_users_role",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&user0007{}, "Role"); err != nil {
+			return err
+		}
+
+		// Antes dos papéis todo usuário podia alterar produtos; quem já existe
+		// continua como editor e os novos cadastros entram como viewer
+		return tx.Exec("UPDATE users SET role = ?", "editor").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&user0007{}, "Role")
+	},
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type product0008 struct {
+	OwnerId *entity.Id `gorm:"size:36;index"`
+}
+
+func (product0008) TableName() string {
+	return "products"
+}
+
+// owner_id fica nulo nos produtos já existentes, que passam a ser alterados só por administradores
+var addProductsOwner = Migration{
+	Version: 8,
+	Name:    "add_products_owner",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&product0008{}, "OwnerId"); err != nil {
+			return err
+		}
+
+		return tx.Exec("CREATE INDEX idx_products_owner_id ON products (owner_id)").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := dropIndexIfExists(tx, "products", "idx_products_owner_id"); err != nil {
+			return err
+		}
+
+		if err := tx.Migrator().DropColumn(&product0008{}, "OwnerId"); err != nil {
+			return err
+		}
+
+		for _, index := range []struct{ name, createSQL string }{
+			{"idx_products_created_at", "CREATE INDEX idx_products_created_at ON products (created_at)"},
+			{"idx_products_created_at_id", "CREATE INDEX idx_products_created_at_id ON products (created_at, id)"},
+			{"idx_products_name_id", "CREATE INDEX idx_products_name_id ON products (name, id)"},
+			{"idx_products_price_id", "CREATE INDEX idx_products_price_id ON products (price, id)"},
+		} {
+			if err := restoreIndex(tx, "products", index.name, index.createSQL); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Os e-mails passam a ser gravados em minúsculas, então o índice único
+// simples já garante a unicidade sem diferenciar maiúsculas
+var addUsersEmailUniqueIndex = Migration{
+	Version: 9,
+	Name:    "add_users_email_unique_index",
+	Up: func(tx *gorm.DB) error {
+		var duplicated []string
+
+		err := tx.Raw("SELECT LOWER(TRIM(email)) FROM users GROUP BY LOWER(TRIM(email)) HAVING COUNT(*) > 1").
+			Scan(&duplicated).Error
+
+		if err != nil {
+			return err
+		}
+
+		// Não há como escolher automaticamente qual conta manter
+		if len(duplicated) > 0 {
+			return fmt.Errorf("e-mails duplicados precisam ser resolvidos antes da migration: %s", strings.Join(duplicated, ", "))
+		}
+
+		if err := tx.Exec("UPDATE users SET email = LOWER(TRIM(email))").Error; err != nil {
+			return err
+		}
+
+		return tx.Exec("CREATE UNIQUE INDEX idx_users_email ON users (email)").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropIndexIfExists(tx, "users", "idx_users_email")
+	},
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type passwordResetToken0010 struct {
+	Id        entity.Id `gorm:"primaryKey;size:36"`
+	UserId    entity.Id `gorm:"size:36;not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
+
+func (passwordResetToken0010) TableName() string {
+	return "password_reset_tokens"
+}
+
+var createPasswordResetTokens = Migration{
+	Version: 10,
+	Name:    "create_password_reset_tokens",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&passwordResetToken0010{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&passwordResetToken0010{})
+	},
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type user0011 struct {
+	VerifiedAt *time.Time
+}
+
+func (user0011) TableName() string {
+	return "users"
+}
+
+var addUsersVerifiedAt = Migration{
+	Version: 11,
+	Name:    "add_users_verified_at",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&user0011{}, "VerifiedAt"); err != nil {
+			return err
+		}
+
+		// Contas criadas antes da verificação continuam podendo entrar
+		return tx.Exec("UPDATE users SET verified_at = ?", time.Now()).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropColumn(&user0011{}, "VerifiedAt"); err != nil {
+			return err
+		}
+
+		return restoreIndex(tx, "users", "idx_users_email", "CREATE UNIQUE INDEX idx_users_email ON users (email)")
+	},
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type apiKey0012 struct {
+	Id         entity.Id `gorm:"primaryKey;size:36"`
+	UserId     entity.Id `gorm:"size:36;not null;index"`
+	Name       string    `gorm:"size:255;not null"`
+	Prefix     string    `gorm:"size:16;not null"`
+	KeyHash    string    `gorm:"size:64;not null;uniqueIndex"`
+	CreatedAt  time.Time `gorm:"not null"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (apiKey0012) TableName() string {
+	return "api_keys"
+}
+
+var createAPIKeys = Migration{
+	Version: 12,
+	Name:    "create_api_keys",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&apiKey0012{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&apiKey0012{})
+	},
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type userIdentity0013 struct {
+	Id        entity.Id `gorm:"primaryKey;size:36"`
+	UserId    entity.Id `gorm:"size:36;not null;index"`
+	Provider  string    `gorm:"size:64;not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email     string    `gorm:"size:255;not null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (userIdentity0013) TableName() string {
+	return "user_identities"
+}
+
+var createUserIdentities = Migration{
+	Version: 13,
+	Name:    "create_user_identities",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&userIdentity0013{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&userIdentity0013{})
+	},
+}
@@ -0,0 +1,21 @@
+package migrations
+
+// All devolve as migrations do projeto. Toda mudança de schema entra aqui
+// como uma nova versão, nunca editando uma migration já publicada.
+func All() []Migration {
+	return []Migration{
+		createProducts,
+		createUsers,
+		addProductsKeysetIndex,
+		addProductsSortIndexes,
+		createRefreshTokens,
+		createRevokedTokens,
+		addUsersRole,
+		addProductsOwner,
+		addUsersEmailUniqueIndex,
+		createPasswordResetTokens,
+		addUsersVerifiedAt,
+		createAPIKeys,
+		createUserIdentities,
+	}
+}
@@ -0,0 +1,248 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNothingToRollback = errors.New("nenhuma migration aplicada")
+	ErrPendingMigrations = errors.New("há migrations pendentes")
+)
+
+// Migration é um passo versionado do schema. Up e Down rodam dentro de uma
+// transação, então devem usar apenas o tx recebido.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration registra cada versão já aplicada no banco
+type SchemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+}
+
+// NewMigrator usa as migrations do projeto quando nenhuma é informada
+func NewMigrator(db *gorm.DB, migrations ...Migration) *Migrator {
+	if len(migrations) == 0 {
+		migrations = All()
+	}
+
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{DB: db, Migrations: sorted}
+}
+
+func (m *Migrator) ensureTable() error {
+	return m.DB.AutoMigrate(&SchemaMigration{})
+}
+
+func (m *Migrator) applied() (map[int64]SchemaMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+
+	if err := m.DB.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]SchemaMigration, len(rows))
+
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+// Up aplica em ordem todas as migrations pendentes
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+
+	for _, migration := range pending {
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down desfaz somente a última migration aplicada
+func (m *Migrator) Down() (*Migration, error) {
+	applied, err := m.applied()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.Migrations) - 1; i >= 0; i-- {
+		migration := m.Migrations[i]
+
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+
+			return tx.Delete(&SchemaMigration{}, "version = ?", migration.Version).Error
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		return &migration, nil
+	}
+
+	return nil, ErrNothingToRollback
+}
+
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Current confere se todas as migrations foram aplicadas sem escrever nada no
+// banco, ao contrário de Pending, que cria a tabela de controle se faltar.
+func (m *Migrator) Current(ctx context.Context) error {
+	db := m.DB.WithContext(ctx)
+
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return fmt.Errorf("%w: %d de %d", ErrPendingMigrations, len(m.Migrations), len(m.Migrations))
+	}
+
+	var versions []int64
+
+	if err := db.Model(&SchemaMigration{}).Pluck("version", &versions).Error; err != nil {
+		return err
+	}
+
+	applied := make(map[int64]bool, len(versions))
+
+	for _, version := range versions {
+		applied[version] = true
+	}
+
+	pending := 0
+
+	for _, migration := range m.Migrations {
+		if !applied[migration.Version] {
+			pending++
+		}
+	}
+
+	if pending > 0 {
+		return fmt.Errorf("%w: %d de %d", ErrPendingMigrations, pending, len(m.Migrations))
+	}
+
+	return nil
+}
+
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]Status, 0, len(m.Migrations))
+
+	for _, migration := range m.Migrations {
+		s := Status{Version: migration.Version, Name: migration.Name}
+
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			s.Applied = true
+			s.AppliedAt = &appliedAt
+		}
+
+		status = append(status, s)
+	}
+
+	return status, nil
+}
+
+// dropIndexIfExists tolera índices que já sumiram: no sqlite o DropColumn
+// recria a tabela e descarta os índices criados por migrations anteriores
+func dropIndexIfExists(tx *gorm.DB, table, name string) error {
+	if !tx.Migrator().HasIndex(table, name) {
+		return nil
+	}
+
+	return tx.Migrator().DropIndex(table, name)
+}
+
+// restoreIndex recria, depois de um DropColumn, um índice que a tabela ainda deve ter
+func restoreIndex(tx *gorm.DB, table, name, createSQL string) error {
+	if tx.Migrator().HasIndex(table, name) {
+		return nil
+	}
+
+	return tx.Exec(createSQL).Error
+}
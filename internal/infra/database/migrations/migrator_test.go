@@ -0,0 +1,276 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDatabase(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cada conexão do sqlite em memória é um banco diferente
+	sqlDB, err := db.DB()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sqlDB.SetMaxOpenConns(1)
+
+	return db
+}
+
+func TestMigrateUp(t *testing.T) {
+	db := setupTestDatabase(t)
+	migrator := NewMigrator(db)
+
+	done, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, done, len(All()))
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// Rodar de novo não aplica nada
+	done, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, done)
+}
+
+// As migrations precisam criar todas as colunas que as entidades usam
+func TestMigrationsMatchEntities(t *testing.T) {
+	db := setupTestDatabase(t)
+	_, err := NewMigrator(db).Up()
+	assert.NoError(t, err)
+
+	models := []interface{}{
+		&entity.Product{},
+		&entity.User{},
+		&entity.RefreshToken{},
+		&entity.RevokedToken{},
+		&entity.PasswordResetToken{},
+		&entity.APIKey{},
+		&entity.UserIdentity{},
+	}
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		assert.NoError(t, stmt.Parse(model))
+		assert.True(t, db.Migrator().HasTable(model), stmt.Schema.Table)
+
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+
+			assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%s.%s", stmt.Schema.Table, field.DBName)
+		}
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	db := setupTestDatabase(t)
+	migrator := NewMigrator(db)
+
+	_, err := migrator.Up()
+	assert.NoError(t, err)
+
+	for range All() {
+		_, err := migrator.Down()
+		assert.NoError(t, err)
+	}
+
+	assert.False(t, db.Migrator().HasTable("products"))
+	assert.False(t, db.Migrator().HasTable("users"))
+	assert.False(t, db.Migrator().HasTable("refresh_tokens"))
+
+	_, err = migrator.Down()
+	assert.ErrorIs(t, err, ErrNothingToRollback)
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, len(All()))
+}
+
+func TestMigrateStatus(t *testing.T) {
+	db := setupTestDatabase(t)
+	migrator := NewMigrator(db, All()[0])
+
+	status, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.Len(t, status, 1)
+	assert.False(t, status[0].Applied)
+
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	status, err = migrator.Status()
+	assert.NoError(t, err)
+	assert.True(t, status[0].Applied)
+	assert.NotNil(t, status[0].AppliedAt)
+}
+
+func TestMigrateCurrent(t *testing.T) {
+	db := setupTestDatabase(t)
+	migrator := NewMigrator(db)
+
+	err := migrator.Current(context.Background())
+	assert.ErrorIs(t, err, ErrPendingMigrations)
+	assert.False(t, db.Migrator().HasTable(&SchemaMigration{}), "Current não deve criar a tabela de controle")
+
+	_, err = NewMigrator(db, All()[0]).Up()
+	assert.NoError(t, err)
+	assert.ErrorIs(t, migrator.Current(context.Background()), ErrPendingMigrations)
+
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.NoError(t, migrator.Current(context.Background()))
+}
+
+func TestMigrateUpKeepsTablesFromAutoMigrate(t *testing.T) {
+	db := setupTestDatabase(t)
+
+	// Schema gerado pelo db.AutoMigrate que rodava antes das migrations
+	assert.NoError(t, db.Exec("CREATE TABLE `products` (`id` text,`name` text,`price` real,`created_at` datetime,PRIMARY KEY (`id`))").Error)
+	assert.NoError(t, db.Exec("CREATE TABLE `users` (`id` text,`name` text,`email` text,`password` text,PRIMARY KEY (`id`))").Error)
+
+	assert.NoError(t, db.Exec("INSERT INTO products (id, name, price, created_at) VALUES (?, ?, ?, ?)",
+		"e0f3c1f4-2b9a-4a3e-9a57-5b8f9d6a1c2e", "Product 1", 10, "2023-12-01 10:00:00").Error)
+
+	_, err := NewMigrator(db).Up()
+	assert.NoError(t, err)
+
+	var count int64
+	db.Table("products").Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestAddUsersRoleKeepsExistingUsersAsEditors(t *testing.T) {
+	db := setupTestDatabase(t)
+	migrator := NewMigrator(db)
+
+	for _, migration := range All() {
+		if migration.Version == addUsersRole.Version {
+			break
+		}
+
+		_, err := NewMigrator(db, migration).Up()
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, db.Exec("INSERT INTO users (id, name, email, password) VALUES (?, ?, ?, ?)",
+		"e0f3c1f4-2b9a-4a3e-9a57-5b8f9d6a1c2e", "Rafael", "rafael@gmail.com", "hash").Error)
+
+	_, err := migrator.Up()
+	assert.NoError(t, err)
+
+	var role string
+	db.Raw("SELECT role FROM users").Scan(&role)
+	assert.Equal(t, entity.RoleEditor, role)
+}
+
+func TestAddUsersEmailUniqueIndex(t *testing.T) {
+	db := setupTestDatabase(t)
+
+	for _, migration := range All() {
+		if migration.Version == addUsersEmailUniqueIndex.Version {
+			break
+		}
+
+		_, err := NewMigrator(db, migration).Up()
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, db.Exec("INSERT INTO users (id, name, email, password, role) VALUES (?, ?, ?, ?, ?)",
+		"e0f3c1f4-2b9a-4a3e-9a57-5b8f9d6a1c2e", "Rafael", " Rafael@Gmail.com", "hash", entity.RoleEditor).Error)
+
+	_, err := NewMigrator(db).Up()
+	assert.NoError(t, err)
+
+	var email string
+	db.Raw("SELECT email FROM users").Scan(&email)
+	assert.Equal(t, "rafael@gmail.com", email)
+
+	err = db.Exec("INSERT INTO users (id, name, email, password, role) VALUES (?, ?, ?, ?, ?)",
+		"0b4e7c55-8d1a-4f6e-a0a3-3f4c2b1d9e8f", "Outro", "rafael@gmail.com", "hash", entity.RoleViewer).Error
+	assert.Error(t, err)
+}
+
+func TestAddUsersEmailUniqueIndexRejectsDuplicates(t *testing.T) {
+	db := setupTestDatabase(t)
+
+	for _, migration := range All() {
+		if migration.Version == addUsersEmailUniqueIndex.Version {
+			break
+		}
+
+		_, err := NewMigrator(db, migration).Up()
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, db.Exec("INSERT INTO users (id, name, email, password, role) VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)",
+		"e0f3c1f4-2b9a-4a3e-9a57-5b8f9d6a1c2e", "Rafael", "rafael@gmail.com", "hash", entity.RoleEditor,
+		"0b4e7c55-8d1a-4f6e-a0a3-3f4c2b1d9e8f", "Rafael", "RAFAEL@gmail.com", "hash", entity.RoleEditor).Error)
+
+	_, err := NewMigrator(db).Up()
+	assert.ErrorContains(t, err, "rafael@gmail.com")
+
+	pending, _ := NewMigrator(db).Pending()
+	assert.Equal(t, addUsersEmailUniqueIndex.Version, pending[0].Version)
+}
+
+func TestAddUsersVerifiedAtDownKeepsEmailIndex(t *testing.T) {
+	db := setupTestDatabase(t)
+	migrator := NewMigrator(db)
+
+	_, err := migrator.Up()
+	assert.NoError(t, err)
+
+	for {
+		migration, err := migrator.Down()
+		assert.NoError(t, err)
+
+		if migration.Version == addUsersVerifiedAt.Version {
+			break
+		}
+	}
+
+	assert.True(t, db.Migrator().HasIndex("users", "idx_users_email"))
+}
+
+func TestAddProductsOwnerDownKeepsProductIndexes(t *testing.T) {
+	db := setupTestDatabase(t)
+	migrator := NewMigrator(db)
+
+	_, err := migrator.Up()
+	assert.NoError(t, err)
+
+	for {
+		migration, err := migrator.Down()
+		assert.NoError(t, err)
+
+		if migration.Version == addProductsOwner.Version {
+			break
+		}
+	}
+
+	for _, index := range []string{"idx_products_created_at", "idx_products_created_at_id", "idx_products_name_id", "idx_products_price_id"} {
+		assert.True(t, db.Migrator().HasIndex("products", index), index)
+	}
+
+	assert.False(t, db.Migrator().HasIndex("products", "idx_products_owner_id"))
+
+	// Subir de novo depois do rollback não pode esbarrar nos índices recriados
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"gorm.io/gorm"
+)
+
+type PasswordResetToken struct {
+	DB *gorm.DB
+}
+
+func NewPasswordResetToken(db *gorm.DB) *PasswordResetToken {
+	return &PasswordResetToken{DB: db}
+}
+
+func (t *PasswordResetToken) WithContext(ctx context.Context) PasswordResetTokenInterface {
+	return &PasswordResetToken{DB: t.DB.WithContext(ctx)}
+}
+
+// Create grava o novo token e descarta os anteriores do usuário, de modo
+// que só o último e-mail enviado continua valendo
+func (t *PasswordResetToken) Create(token *entity.PasswordResetToken) error {
+	return t.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", token.UserId).Delete(&entity.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(token).Error
+	})
+}
+
+func (t *PasswordResetToken) FindByHash(hash string) (*entity.PasswordResetToken, error) {
+	var token entity.PasswordResetToken
+
+	if err := t.DB.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Use marca o token como utilizado. A condição em used_at impede que duas
+// requisições troquem a senha com o mesmo token.
+func (t *PasswordResetToken) Use(token *entity.PasswordResetToken) error {
+	now := time.Now()
+
+	result := t.DB.Model(&entity.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", token.Id).
+		Update("used_at", now)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrTokenAlreadyUsed
+	}
+
+	token.UsedAt = &now
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetTokenCreateAndFindByHash(t *testing.T) {
+	dataRef := &entity.PasswordResetToken{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	userId := pkg.NewId()
+	first, firstPlain, _ := entity.NewPasswordResetToken(userId, time.Hour)
+	second, secondPlain, _ := entity.NewPasswordResetToken(userId, time.Hour)
+
+	tokenDb := NewPasswordResetToken(db)
+	assert.NoError(t, tokenDb.Create(first))
+	assert.NoError(t, tokenDb.Create(second))
+
+	found, err := tokenDb.FindByHash(entity.HashToken(secondPlain))
+	assert.NoError(t, err)
+	assert.Equal(t, second.Id, found.Id)
+	assert.Nil(t, found.UsedAt)
+
+	// Um novo pedido invalida o anterior
+	_, err = tokenDb.FindByHash(entity.HashToken(firstPlain))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPasswordResetTokenUse(t *testing.T) {
+	dataRef := &entity.PasswordResetToken{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	token, plain, _ := entity.NewPasswordResetToken(pkg.NewId(), time.Hour)
+
+	tokenDb := NewPasswordResetToken(db)
+	assert.NoError(t, tokenDb.Create(token))
+
+	stale, _ := tokenDb.FindByHash(entity.HashToken(plain))
+
+	assert.NoError(t, tokenDb.Use(token))
+	assert.True(t, token.IsUsed())
+
+	found, _ := tokenDb.FindByHash(entity.HashToken(plain))
+	assert.True(t, found.IsUsed())
+
+	// Uma cópia lida antes do uso não pode ser usada de novo
+	assert.ErrorIs(t, tokenDb.Use(stale), ErrTokenAlreadyUsed)
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"context"
+
 	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
 	"gorm.io/gorm"
 )
@@ -13,6 +15,10 @@ func NewProduct(db *gorm.DB) *Product {
 	return &Product{DB: db}
 }
 
+func (p *Product) WithContext(ctx context.Context) ProductInterface {
+	return &Product{DB: p.DB.WithContext(ctx)}
+}
+
 func (p *Product) Create(product *entity.Product) error {
 	return p.DB.Create(product).Error
 }
@@ -47,20 +53,76 @@ func (p *Product) Delete(id string) error {
 	return p.DB.Delete(product).Error
 }
 
-func (p *Product) FindAll(page, limit int, sort string) ([]entity.Product, error) {
+// FindAll devolve a página pedida e o total de produtos que atendem aos filtros
+func (p *Product) FindAll(query ProductQuery) ([]entity.Product, int64, error) {
 	var products []entity.Product
+	var total int64
 	var err error
 
-	if sort != "" && sort != "asc" && sort != "desc" {
-		sort = "asc"
+	query = query.normalized()
+
+	if err = query.filter(p.DB.Model(&entity.Product{})).Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
 
-	if page != 0 && limit != 0 {
-		err = p.DB.Limit(limit).Offset((page - 1) * limit).Order("created_at " + sort).Find(&products).Error
+	db := query.filter(p.DB).Order(query.order())
+
+	if query.Page != 0 && query.Limit != 0 {
+		err = db.Limit(query.Limit).Offset((query.Page - 1) * query.Limit).Find(&products).Error
 
 	} else {
-		err = p.DB.Order("created_at " + sort).Find(&products).Error
+		err = db.Find(&products).Error
+	}
+
+	return products, total, err
+}
+
+// FindAfter pagina por keyset a partir de query.Cursor, estável mesmo com inserções
+// durante a iteração. O próximo cursor é nil quando não há mais itens.
+func (p *Product) FindAfter(query ProductQuery) ([]entity.Product, *Cursor, int64, error) {
+	var products []entity.Product
+	var total int64
+
+	query = query.normalized()
+
+	if query.Cursor != nil && query.Cursor.SortBy != query.SortBy {
+		return nil, nil, 0, ErrInvalidCursor
+	}
+
+	if err := query.filter(p.DB.Model(&entity.Product{})).Count(&total).Error; err != nil {
+		return nil, nil, 0, err
+	}
+
+	db := query.filter(p.DB).Order(query.order()).Limit(query.Limit + 1)
+
+	if query.Cursor != nil {
+		op := ">"
+
+		if query.Sort == "desc" {
+			op = "<"
+		}
+
+		value := query.Cursor.value()
+		db = db.Where("("+query.SortBy+" "+op+" ? OR ("+query.SortBy+" = ? AND id "+op+" ?))", value, value, query.Cursor.Id)
+	}
+
+	if err := db.Find(&products).Error; err != nil {
+		return nil, nil, 0, err
+	}
+
+	if len(products) <= query.Limit {
+		return products, nil, total, nil
+	}
+
+	products = products[:query.Limit]
+
+	return products, CursorFor(products[query.Limit-1], query.SortBy), total, nil
+}
+
+func normalizeSort(sort string) string {
+	if sort != "asc" && sort != "desc" {
+		return "asc"
 	}
 
-	return products, err
+	return sort
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
 	"github.com/stretchr/testify/assert"
@@ -26,6 +27,69 @@ func TestCreateNewProduct(t *testing.T) {
 	assert.NotEmpty(t, product.Id)
 }
 
+func TestCreateProductWithOwner(t *testing.T) {
+	dataRef := &entity.Product{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	product, _ := entity.NewProduct("Product 1", 10.0)
+	product.SetOwner(user.Id)
+
+	products := NewProduct(db)
+	assert.NoError(t, products.Create(product))
+
+	found, err := products.FindById(product.Id.String())
+	assert.NoError(t, err)
+	assert.True(t, found.IsOwnedBy(user.Id.String()))
+
+	// Produto sem dono continua sendo gravado com owner_id nulo
+	legacy, _ := entity.NewProduct("Product 2", 10.0)
+	assert.NoError(t, products.Create(legacy))
+
+	found, err = products.FindById(legacy.Id.String())
+	assert.NoError(t, err)
+	assert.Nil(t, found.OwnerId)
+}
+
+func TestFindAllProductByOwner(t *testing.T) {
+	dataRef := &entity.Product{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	owner, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	other, _ := entity.NewUser("Outro", "outro@gmail.com", "rafa2024x")
+
+	for i := 1; i <= 6; i++ {
+		product, _ := entity.NewProduct(fmt.Sprintf("Product %d", i), 10.0)
+
+		if i%2 == 0 {
+			product.SetOwner(owner.Id)
+		} else {
+			product.SetOwner(other.Id)
+		}
+
+		db.Create(product)
+	}
+
+	productsDb := NewProduct(db)
+	products, total, err := productsDb.FindAll(ProductQuery{OwnerId: owner.Id.String()})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+
+	for _, product := range products {
+		assert.True(t, product.IsOwnedBy(owner.Id.String()))
+	}
+}
+
 func TestFindAllProduct(t *testing.T) {
 	dataRef := &entity.Product{}
 
@@ -44,19 +108,20 @@ func TestFindAllProduct(t *testing.T) {
 	}
 
 	productsDb := NewProduct(db)
-	products, err := productsDb.FindAll(1, 10, "asc")
+	products, total, err := productsDb.FindAll(ProductQuery{Page: 1, Limit: 10, Sort: "asc"})
 	assert.NoError(t, err)
+	assert.Equal(t, int64(23), total)
 	assert.Len(t, products, 10)
 	assert.Equal(t, "Product 1", products[0].Name)
 	assert.Equal(t, "Product 10", products[9].Name)
 
-	products, err = productsDb.FindAll(2, 10, "asc")
+	products, _, err = productsDb.FindAll(ProductQuery{Page: 2, Limit: 10, Sort: "asc"})
 	assert.NoError(t, err)
 	assert.Len(t, products, 10)
 	assert.Equal(t, "Product 11", products[0].Name)
 	assert.Equal(t, "Product 20", products[9].Name)
 
-	products, err = productsDb.FindAll(3, 10, "asc")
+	products, _, err = productsDb.FindAll(ProductQuery{Page: 3, Limit: 10, Sort: "asc"})
 	assert.NoError(t, err)
 	assert.Len(t, products, 3)
 	assert.Equal(t, "Product 21", products[0].Name)
@@ -64,6 +129,161 @@ func TestFindAllProduct(t *testing.T) {
 
 }
 
+func TestFindAfterProduct(t *testing.T) {
+	dataRef := &entity.Product{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	for i := 1; i < 24; i++ {
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), rand.Float64()*100)
+		assert.NoError(t, err)
+		db.Create(product)
+	}
+
+	productsDb := NewProduct(db)
+	products, next, total, err := productsDb.FindAfter(ProductQuery{Limit: 10, Sort: "asc"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(23), total)
+	assert.Len(t, products, 10)
+	assert.Equal(t, "Product 1", products[0].Name)
+	assert.NotNil(t, next)
+
+	// O cursor sobrevive à ida e volta pelo cliente
+	next, err = DecodeCursor(next.Encode())
+	assert.NoError(t, err)
+
+	products, next, _, err = productsDb.FindAfter(ProductQuery{Cursor: next, Limit: 10, Sort: "asc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Product 11", products[0].Name)
+
+	products, next, _, err = productsDb.FindAfter(ProductQuery{Cursor: next, Limit: 10, Sort: "asc"})
+	assert.NoError(t, err)
+	assert.Len(t, products, 3)
+	assert.Equal(t, "Product 23", products[2].Name)
+	assert.Nil(t, next)
+
+	products, next, _, err = productsDb.FindAfter(ProductQuery{Limit: 5, Sort: "desc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Product 23", products[0].Name)
+
+	products, _, _, err = productsDb.FindAfter(ProductQuery{Cursor: next, Limit: 5, Sort: "desc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Product 18", products[0].Name)
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	_, err := DecodeCursor("not-a-cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = DecodeCursor((&Cursor{}).Encode())
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = DecodeCursor((&Cursor{SortBy: "password", Id: "e0f3c1f4-2b9a-4a3e-9a57-5b8f9d6a1c2e"}).Encode())
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestFindAllProductFilters(t *testing.T) {
+	dataRef := &entity.Product{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	names := []string{"Geladeira", "Fogão", "Geladeira Duplex", "Micro-ondas", "100% Algodão"}
+	start := time.Now()
+
+	for i, name := range names {
+		product, err := entity.NewProduct(name, float64(i+1)*100)
+		assert.NoError(t, err)
+		product.CreatedAt = start.Add(time.Duration(i) * time.Hour)
+		db.Create(product)
+	}
+
+	productsDb := NewProduct(db)
+
+	products, total, err := productsDb.FindAll(ProductQuery{Name: "geladeira"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, products, 2)
+
+	// % digitado pelo cliente não vira curinga
+	products, _, err = productsDb.FindAll(ProductQuery{Name: "0%"})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "100% Algodão", products[0].Name)
+
+	minPrice, maxPrice := 200.0, 400.0
+	products, total, err = productsDb.FindAll(ProductQuery{MinPrice: &minPrice, MaxPrice: &maxPrice, SortBy: SortByPrice, Sort: "desc"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, "Micro-ondas", products[0].Name)
+	assert.Equal(t, "Fogão", products[2].Name)
+
+	from, to := start.Add(30*time.Minute), start.Add(150*time.Minute)
+	products, _, err = productsDb.FindAll(ProductQuery{CreatedFrom: &from, CreatedTo: &to})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+	assert.Equal(t, "Fogão", products[0].Name)
+
+	products, _, err = productsDb.FindAll(ProductQuery{SortBy: SortByName, Page: 1, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "100% Algodão", products[0].Name)
+	assert.Equal(t, "Fogão", products[1].Name)
+
+	// SortBy desconhecido volta para created_at
+	products, _, err = productsDb.FindAll(ProductQuery{SortBy: "price; DROP TABLE products"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Geladeira", products[0].Name)
+}
+
+func TestFindAfterProductSortedByName(t *testing.T) {
+	dataRef := &entity.Product{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, name := range []string{"c", "a", "e", "b", "d"} {
+		product, err := entity.NewProduct(name, 10)
+		assert.NoError(t, err)
+		db.Create(product)
+	}
+
+	productsDb := NewProduct(db)
+	query := ProductQuery{SortBy: SortByName, Limit: 2}
+
+	var names []string
+
+	for {
+		products, next, _, err := productsDb.FindAfter(query)
+		assert.NoError(t, err)
+
+		for _, product := range products {
+			names = append(names, product.Name)
+		}
+
+		if next == nil {
+			break
+		}
+
+		query.Cursor = next
+	}
+
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, names)
+
+	// Cursor gerado para outra ordenação é rejeitado
+	_, _, _, err = productsDb.FindAfter(ProductQuery{SortBy: SortByPrice, Limit: 2, Cursor: query.Cursor})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
 func TestFindById(t *testing.T) {
 	dataRef := &entity.Product{}
 
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"gorm.io/gorm"
+)
+
+const (
+	SortByCreatedAt = "created_at"
+	SortByName      = "name"
+	SortByPrice     = "price"
+)
+
+// ProductQuery reúne paginação, ordenação e filtros da listagem de produtos.
+// Campos zerados (ou nil) não filtram nada.
+type ProductQuery struct {
+	Page        int
+	Limit       int
+	Sort        string
+	SortBy      string
+	Cursor      *Cursor
+	Name        string
+	OwnerId     string
+	MinPrice    *float64
+	MaxPrice    *float64
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+func IsValidSortBy(sortBy string) bool {
+	return sortBy == SortByCreatedAt || sortBy == SortByName || sortBy == SortByPrice
+}
+
+// normalized garante que Sort e SortBy só tenham valores conhecidos, já que vão direto para o ORDER BY
+func (q ProductQuery) normalized() ProductQuery {
+	q.Sort = normalizeSort(q.Sort)
+
+	if !IsValidSortBy(q.SortBy) {
+		q.SortBy = SortByCreatedAt
+	}
+
+	return q
+}
+
+func (q ProductQuery) order() string {
+	return q.SortBy + " " + q.Sort + ", id " + q.Sort
+}
+
+// filter aplica apenas os filtros, sem paginação, para servir também à contagem
+func (q ProductQuery) filter(db *gorm.DB) *gorm.DB {
+	if q.Name != "" {
+		db = db.Where("LOWER(name) LIKE ? ESCAPE '!'", "%"+escapeLike(strings.ToLower(q.Name))+"%")
+	}
+
+	if q.OwnerId != "" {
+		db = db.Where("owner_id = ?", q.OwnerId)
+	}
+
+	if q.MinPrice != nil {
+		db = db.Where("price >= ?", *q.MinPrice)
+	}
+
+	if q.MaxPrice != nil {
+		db = db.Where("price <= ?", *q.MaxPrice)
+	}
+
+	if q.CreatedFrom != nil {
+		db = db.Where("created_at >= ?", *q.CreatedFrom)
+	}
+
+	if q.CreatedTo != nil {
+		db = db.Where("created_at <= ?", *q.CreatedTo)
+	}
+
+	return db
+}
+
+// Matches avalia os filtros em memória, para implementações de teste do ProductInterface
+func (q ProductQuery) Matches(product entity.Product) bool {
+	if q.Name != "" && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+
+	if q.OwnerId != "" && !product.IsOwnedBy(q.OwnerId) {
+		return false
+	}
+
+	if q.MinPrice != nil && product.Price < *q.MinPrice {
+		return false
+	}
+
+	if q.MaxPrice != nil && product.Price > *q.MaxPrice {
+		return false
+	}
+
+	if q.CreatedFrom != nil && product.CreatedAt.Before(*q.CreatedFrom) {
+		return false
+	}
+
+	if q.CreatedTo != nil && product.CreatedAt.After(*q.CreatedTo) {
+		return false
+	}
+
+	return true
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
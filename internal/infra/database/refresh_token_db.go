@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type RefreshToken struct {
+	DB *gorm.DB
+}
+
+func NewRefreshToken(db *gorm.DB) *RefreshToken {
+	return &RefreshToken{DB: db}
+}
+
+func (t *RefreshToken) WithContext(ctx context.Context) RefreshTokenInterface {
+	return &RefreshToken{DB: t.DB.WithContext(ctx)}
+}
+
+func (t *RefreshToken) Create(token *entity.RefreshToken) error {
+	return t.DB.Create(token).Error
+}
+
+func (t *RefreshToken) FindByHash(hash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+
+	if err := t.DB.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Rotate revoga o token atual e grava o substituto na mesma transação. A
+// condição em revoked_at impede que duas requisições troquem o mesmo token.
+func (t *RefreshToken) Rotate(current, next *entity.RefreshToken) error {
+	return t.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		result := tx.Model(&entity.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.Id).
+			Updates(map[string]interface{}{"revoked_at": now, "replaced_by_id": next.Id})
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrTokenAlreadyRevoked
+		}
+
+		current.RevokedAt = &now
+		current.ReplacedById = &next.Id
+
+		return tx.Create(next).Error
+	})
+}
+
+// RevokeFamily revoga todos os tokens ainda ativos gerados a partir do mesmo login
+func (t *RefreshToken) RevokeFamily(familyId pkg.Id) error {
+	return t.DB.Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUser encerra todas as sessões do usuário, como após a troca de senha
+func (t *RefreshToken) RevokeUser(userId pkg.Id) error {
+	return t.DB.Model(&entity.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokenCreateAndFindByHash(t *testing.T) {
+	dataRef := &entity.RefreshToken{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	token, plain, err := entity.NewRefreshToken(pkg.NewId(), pkg.NewId(), time.Hour)
+	assert.NoError(t, err)
+
+	tokenDb := NewRefreshToken(db)
+	assert.NoError(t, tokenDb.Create(token))
+
+	found, err := tokenDb.FindByHash(entity.HashToken(plain))
+	assert.NoError(t, err)
+	assert.Equal(t, token.Id, found.Id)
+	assert.Equal(t, token.FamilyId, found.FamilyId)
+	assert.Nil(t, found.RevokedAt)
+
+	_, err = tokenDb.FindByHash(entity.HashToken("outro"))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRefreshTokenRotate(t *testing.T) {
+	dataRef := &entity.RefreshToken{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	userId, familyId := pkg.NewId(), pkg.NewId()
+	current, currentPlain, _ := entity.NewRefreshToken(userId, familyId, time.Hour)
+	next, nextPlain, _ := entity.NewRefreshToken(userId, familyId, time.Hour)
+
+	tokenDb := NewRefreshToken(db)
+	assert.NoError(t, tokenDb.Create(current))
+	assert.NoError(t, tokenDb.Rotate(current, next))
+
+	found, err := tokenDb.FindByHash(entity.HashToken(currentPlain))
+	assert.NoError(t, err)
+	assert.True(t, found.IsRevoked())
+	assert.Equal(t, next.Id, *found.ReplacedById)
+
+	found, err = tokenDb.FindByHash(entity.HashToken(nextPlain))
+	assert.NoError(t, err)
+	assert.False(t, found.IsRevoked())
+
+	// O mesmo token não pode ser trocado duas vezes
+	other, _, _ := entity.NewRefreshToken(userId, familyId, time.Hour)
+	assert.ErrorIs(t, tokenDb.Rotate(current, other), ErrTokenAlreadyRevoked)
+}
+
+func TestRefreshTokenRevokeFamily(t *testing.T) {
+	dataRef := &entity.RefreshToken{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	userId, familyId := pkg.NewId(), pkg.NewId()
+	first, firstPlain, _ := entity.NewRefreshToken(userId, familyId, time.Hour)
+	second, secondPlain, _ := entity.NewRefreshToken(userId, familyId, time.Hour)
+	other, otherPlain, _ := entity.NewRefreshToken(userId, pkg.NewId(), time.Hour)
+
+	tokenDb := NewRefreshToken(db)
+	assert.NoError(t, tokenDb.Create(first))
+	assert.NoError(t, tokenDb.Create(second))
+	assert.NoError(t, tokenDb.Create(other))
+
+	assert.NoError(t, tokenDb.RevokeFamily(familyId))
+
+	for _, plain := range []string{firstPlain, secondPlain} {
+		found, err := tokenDb.FindByHash(entity.HashToken(plain))
+		assert.NoError(t, err)
+		assert.True(t, found.IsRevoked())
+	}
+
+	found, err := tokenDb.FindByHash(entity.HashToken(otherPlain))
+	assert.NoError(t, err)
+	assert.False(t, found.IsRevoked())
+}
+
+func TestRefreshTokenRevokeUser(t *testing.T) {
+	dataRef := &entity.RefreshToken{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	userId := pkg.NewId()
+	first, firstPlain, _ := entity.NewRefreshToken(userId, pkg.NewId(), time.Hour)
+	second, secondPlain, _ := entity.NewRefreshToken(userId, pkg.NewId(), time.Hour)
+	other, otherPlain, _ := entity.NewRefreshToken(pkg.NewId(), pkg.NewId(), time.Hour)
+
+	tokenDb := NewRefreshToken(db)
+	assert.NoError(t, tokenDb.Create(first))
+	assert.NoError(t, tokenDb.Create(second))
+	assert.NoError(t, tokenDb.Create(other))
+
+	assert.NoError(t, tokenDb.RevokeUser(userId))
+
+	for _, plain := range []string{firstPlain, secondPlain} {
+		found, err := tokenDb.FindByHash(entity.HashToken(plain))
+		assert.NoError(t, err)
+		assert.True(t, found.IsRevoked())
+	}
+
+	found, err := tokenDb.FindByHash(entity.HashToken(otherPlain))
+	assert.NoError(t, err)
+	assert.False(t, found.IsRevoked())
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevokedToken struct {
+	DB *gorm.DB
+}
+
+func NewRevokedToken(db *gorm.DB) *RevokedToken {
+	return &RevokedToken{DB: db}
+}
+
+func (t *RevokedToken) WithContext(ctx context.Context) RevokedTokenInterface {
+	return &RevokedToken{DB: t.DB.WithContext(ctx)}
+}
+
+// Revoke é idempotente: revogar o mesmo jti duas vezes não é erro
+func (t *RevokedToken) Revoke(jti string, expiresAt time.Time) error {
+	return t.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&entity.RevokedToken{Jti: jti, ExpiresAt: expiresAt}).Error
+}
+
+func (t *RevokedToken) IsRevoked(jti string) (bool, error) {
+	var count int64
+
+	err := t.DB.Model(&entity.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+
+	return count > 0, err
+}
+
+// DeleteExpired limpa entradas de tokens que já expiraram por conta própria
+func (t *RevokedToken) DeleteExpired() (int64, error) {
+	result := t.DB.Where("expires_at < ?", time.Now()).Delete(&entity.RevokedToken{})
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokedToken(t *testing.T) {
+	dataRef := &entity.RevokedToken{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	denylist := NewRevokedToken(db)
+
+	revoked, err := denylist.IsRevoked("jti-1")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, denylist.Revoke("jti-1", time.Now().Add(time.Hour)))
+	assert.NoError(t, denylist.Revoke("jti-1", time.Now().Add(time.Hour)))
+
+	revoked, err = denylist.IsRevoked("jti-1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestRevokedTokenDeleteExpired(t *testing.T) {
+	dataRef := &entity.RevokedToken{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	denylist := NewRevokedToken(db)
+	assert.NoError(t, denylist.Revoke("expirado", time.Now().Add(-time.Minute)))
+	assert.NoError(t, denylist.Revoke("ativo", time.Now().Add(time.Hour)))
+
+	deleted, err := denylist.DeleteExpired()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	revoked, _ := denylist.IsRevoked("ativo")
+	assert.True(t, revoked)
+}
@@ -1,6 +1,9 @@
 package database
 
 import (
+	"context"
+	"errors"
+
 	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
 	"gorm.io/gorm"
 )
@@ -13,16 +16,134 @@ func NewUser(db *gorm.DB) *User {
 	return &User{DB: db}
 }
 
+func (u *User) WithContext(ctx context.Context) UserInterface {
+	return &User{DB: u.DB.WithContext(ctx)}
+}
+
 func (u *User) Create(user *entity.User) error {
-	return u.DB.Create(user).Error
+	_, err := u.FindByEmail(user.Email)
+
+	if err == nil {
+		return ErrEmailAlreadyExists
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	// O índice único cobre cadastros simultâneos que passaram pela consulta acima
+	err = u.DB.Create(user).Error
+
+	if errors.Is(translateError(u.DB, err), gorm.ErrDuplicatedKey) {
+		return ErrEmailAlreadyExists
+	}
+
+	return err
 }
 
 func (u *User) FindByEmail(email string) (*entity.User, error) {
 	var user entity.User
 
-	if err := u.DB.Where("email=?", email).First(&user).Error; err != nil {
+	if err := u.DB.Where("email=?", entity.NormalizeEmail(email)).First(&user).Error; err != nil {
 		return nil, err
 	}
 
 	return &user, nil
 }
+
+func (u *User) FindById(id string) (*entity.User, error) {
+	var user entity.User
+
+	if err := u.DB.First(&user, "id=?", id).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (u *User) Update(user *entity.User) error {
+	_, err := u.FindById(user.Id.String())
+
+	if err != nil {
+		return err
+	}
+
+	user.Email = entity.NormalizeEmail(user.Email)
+	other, err := u.FindByEmail(user.Email)
+
+	if err == nil && other.Id != user.Id {
+		return ErrEmailAlreadyExists
+	}
+
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	err = u.DB.Save(user).Error
+
+	if errors.Is(translateError(u.DB, err), gorm.ErrDuplicatedKey) {
+		return ErrEmailAlreadyExists
+	}
+
+	return err
+}
+
+// Delete remove o usuário junto com os tokens dele. Os produtos
+// continuam cadastrados, sem dono, como os criados antes do controle de dono.
+func (u *User) Delete(id string) error {
+	user, err := u.FindById(id)
+
+	if err != nil {
+		return err
+	}
+
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.Product{}).Where("owner_id = ?", user.Id).Update("owner_id", nil).Error
+
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", user.Id).Delete(&entity.RefreshToken{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", user.Id).Delete(&entity.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", user.Id).Delete(&entity.APIKey{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", user.Id).Delete(&entity.UserIdentity{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(user).Error
+	})
+}
+
+// FindAll devolve a página pedida, ordenada por e-mail, e o total de usuários
+func (u *User) FindAll(page, limit int) ([]entity.User, int64, error) {
+	var users []entity.User
+	var total int64
+
+	if err := u.DB.Model(&entity.User{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	db := u.DB.Order("email asc, id asc")
+
+	if limit > 0 {
+		db = db.Limit(limit).Offset((page - 1) * limit)
+	}
+
+	err := db.Find(&users).Error
+
+	return users, total, err
+}
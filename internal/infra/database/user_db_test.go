@@ -1,10 +1,14 @@
 package database
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 func TestUserCreate(t *testing.T) {
@@ -16,7 +20,7 @@ func TestUserCreate(t *testing.T) {
 		t.Error(err)
 	}
 
-	User, _ := entity.NewUser("Rafael", "rafel@gmail.com", "123456")
+	User, _ := entity.NewUser("Rafael", "rafel@gmail.com", "rafa2024x")
 	UserDb := NewUser(db)
 
 	err = UserDb.Create(User)
@@ -40,7 +44,7 @@ func TestFindByEmail(t *testing.T) {
 		t.Error(err)
 	}
 
-	User, _ := entity.NewUser("Rafael", "rafel@gmail.com", "123456")
+	User, _ := entity.NewUser("Rafael", "rafel@gmail.com", "rafa2024x")
 	UserDb := NewUser(db)
 
 	err = UserDb.Create(User)
@@ -57,3 +61,196 @@ func TestFindByEmail(t *testing.T) {
 	assert.Equal(t, User.Email, userFound.Email)
 	assert.NotNil(t, userFound.Password)
 }
+
+func TestUserFindById(t *testing.T) {
+	dataRef := &entity.User{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	User, _ := entity.NewUser("Rafael", "rafel@gmail.com", "rafa2024x")
+	UserDb := NewUser(db)
+	assert.Nil(t, UserDb.Create(User))
+
+	userFound, err := UserDb.FindById(User.Id.String())
+	assert.Nil(t, err)
+	assert.Equal(t, User.Email, userFound.Email)
+	assert.Equal(t, entity.RoleViewer, userFound.Role)
+
+	_, err = UserDb.FindById("e0f3c1f4-2b9a-4a3e-9a57-5b8f9d6a1c2e")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUserUpdate(t *testing.T) {
+	dataRef := &entity.User{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	User, _ := entity.NewUser("Rafael", "rafel@gmail.com", "rafa2024x")
+	UserDb := NewUser(db)
+	assert.Nil(t, UserDb.Create(User))
+
+	assert.Nil(t, User.SetRole(entity.RoleAdmin))
+	assert.Nil(t, UserDb.Update(User))
+
+	userFound, err := UserDb.FindById(User.Id.String())
+	assert.Nil(t, err)
+	assert.Equal(t, entity.RoleAdmin, userFound.Role)
+
+	other, _ := entity.NewUser("Outro", "outro@gmail.com", "rafa2024x")
+	assert.ErrorIs(t, UserDb.Update(other), ErrNotFound)
+}
+
+func TestUserCreateDuplicatedEmail(t *testing.T) {
+	dataRef := &entity.User{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	User, _ := entity.NewUser("Rafael", "rafel@gmail.com", "rafa2024x")
+	UserDb := NewUser(db)
+	assert.Nil(t, UserDb.Create(User))
+
+	other, _ := entity.NewUser("Outro", "Rafel@Gmail.com", "rafa2024x")
+	assert.ErrorIs(t, UserDb.Create(other), ErrEmailAlreadyExists)
+
+	userFound, err := UserDb.FindByEmail(" RAFEL@gmail.com")
+	assert.Nil(t, err)
+	assert.Equal(t, User.Id, userFound.Id)
+}
+
+func TestUserCreateTranslatesUniqueIndex(t *testing.T) {
+	dataRef := &entity.User{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Nil(t, db.Exec("CREATE UNIQUE INDEX idx_users_email ON users (email)").Error)
+
+	User, _ := entity.NewUser("Rafael", "rafel@gmail.com", "rafa2024x")
+	assert.Nil(t, db.Create(User).Error)
+
+	// Simula outro cadastro gravado entre a consulta e o insert
+	other, _ := entity.NewUser("Outro", "rafel@gmail.com", "rafa2024x")
+	err = db.Create(other).Error
+	assert.ErrorIs(t, translateError(db, err), gorm.ErrDuplicatedKey)
+}
+
+func TestUserUpdateDuplicatedEmail(t *testing.T) {
+	dataRef := &entity.User{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	User, _ := entity.NewUser("Rafael", "rafel@gmail.com", "rafa2024x")
+	other, _ := entity.NewUser("Outro", "outro@gmail.com", "rafa2024x")
+	UserDb := NewUser(db)
+	assert.Nil(t, UserDb.Create(User))
+	assert.Nil(t, UserDb.Create(other))
+
+	other.Email = "RAFEL@gmail.com"
+	assert.ErrorIs(t, UserDb.Update(other), ErrEmailAlreadyExists)
+
+	// Manter o próprio e-mail não é conflito
+	User.Name = "Rafael Souza"
+	User.Email = "Rafel@Gmail.com"
+	assert.Nil(t, UserDb.Update(User))
+
+	userFound, err := UserDb.FindById(User.Id.String())
+	assert.Nil(t, err)
+	assert.Equal(t, "Rafael Souza", userFound.Name)
+	assert.Equal(t, "rafel@gmail.com", userFound.Email)
+}
+
+func TestUserDelete(t *testing.T) {
+	dataRef := &entity.User{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Nil(t, db.AutoMigrate(&entity.Product{}, &entity.RefreshToken{}, &entity.PasswordResetToken{}, &entity.APIKey{}, &entity.UserIdentity{}))
+
+	User, _ := entity.NewUser("Rafael", "rafel@gmail.com", "rafa2024x")
+	UserDb := NewUser(db)
+	assert.Nil(t, UserDb.Create(User))
+
+	product, _ := entity.NewProduct("Product 1", 10.0)
+	product.SetOwner(User.Id)
+	assert.Nil(t, NewProduct(db).Create(product))
+
+	token, _, _ := entity.NewRefreshToken(User.Id, pkg.NewId(), time.Hour)
+	assert.Nil(t, NewRefreshToken(db).Create(token))
+
+	key, _, _ := entity.NewAPIKey(User.Id, "importador")
+	assert.Nil(t, NewAPIKey(db).Create(key))
+
+	assert.Nil(t, NewUserIdentity(db).Create(entity.NewUserIdentity(User.Id, "google", "123", User.Email)))
+
+	assert.Nil(t, UserDb.Delete(User.Id.String()))
+
+	_, err = UserDb.FindById(User.Id.String())
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = NewRefreshToken(db).FindByHash(token.TokenHash)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = NewAPIKey(db).FindByHash(key.KeyHash)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = NewUserIdentity(db).FindBySubject("google", "123")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	productFound, err := NewProduct(db).FindById(product.Id.String())
+	assert.Nil(t, err)
+	assert.Nil(t, productFound.OwnerId)
+
+	assert.ErrorIs(t, UserDb.Delete(User.Id.String()), ErrNotFound)
+}
+
+func TestUserFindAll(t *testing.T) {
+	dataRef := &entity.User{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	UserDb := NewUser(db)
+
+	for i := 1; i <= 25; i++ {
+		User, _ := entity.NewUser(fmt.Sprintf("User %d", i), fmt.Sprintf("user%02d@gmail.com", i), "rafa2024x")
+		assert.Nil(t, UserDb.Create(User))
+	}
+
+	users, total, err := UserDb.FindAll(1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(25), total)
+	assert.Len(t, users, 10)
+	assert.Equal(t, "user01@gmail.com", users[0].Email)
+
+	users, _, err = UserDb.FindAll(3, 10)
+	assert.Nil(t, err)
+	assert.Len(t, users, 5)
+	assert.Equal(t, "user21@gmail.com", users[0].Email)
+	assert.Equal(t, "user25@gmail.com", users[4].Email)
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"gorm.io/gorm"
+)
+
+var ErrIdentityAlreadyLinked = errors.New("identidade já vinculada")
+
+type UserIdentity struct {
+	DB *gorm.DB
+}
+
+func NewUserIdentity(db *gorm.DB) *UserIdentity {
+	return &UserIdentity{DB: db}
+}
+
+func (i *UserIdentity) WithContext(ctx context.Context) UserIdentityInterface {
+	return &UserIdentity{DB: i.DB.WithContext(ctx)}
+}
+
+func (i *UserIdentity) Create(identity *entity.UserIdentity) error {
+	err := translateError(i.DB, i.DB.Create(identity).Error)
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrIdentityAlreadyLinked
+	}
+
+	return err
+}
+
+func (i *UserIdentity) FindBySubject(provider, subject string) (*entity.UserIdentity, error) {
+	var identity entity.UserIdentity
+
+	if err := i.DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserIdentityCreateAndFind(t *testing.T) {
+	dataRef := &entity.UserIdentity{}
+
+	db, err := setupTestDatabase(dataRef)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	// O AutoMigrate não cria o índice composto das migrations
+	assert.NoError(t, db.Exec("CREATE UNIQUE INDEX idx_user_identities_provider_subject ON user_identities (provider, subject)").Error)
+
+	userId := pkg.NewId()
+	identityDb := NewUserIdentity(db)
+	assert.NoError(t, identityDb.Create(entity.NewUserIdentity(userId, "google", "123", "Rafael@Gmail.com")))
+
+	found, err := identityDb.FindBySubject("google", "123")
+	assert.NoError(t, err)
+	assert.Equal(t, userId, found.UserId)
+	assert.Equal(t, "rafael@gmail.com", found.Email)
+
+	_, err = identityDb.FindBySubject("keycloak", "123")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = identityDb.Create(entity.NewUserIdentity(pkg.NewId(), "google", "123", "outro@gmail.com"))
+	assert.ErrorIs(t, err, ErrIdentityAlreadyLinked)
+}
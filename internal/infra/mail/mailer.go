@@ -0,0 +1,101 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrUnsupportedMailer = errors.New("driver de e-mail não suportado")
+
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+)
+
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer é o ponto de extensão para o envio de e-mails. As implementações
+// locais permitem testar os fluxos sem um servidor SMTP.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New escolhe a implementação a partir do MAIL_DRIVER
+func New(driver, dir string) (Mailer, error) {
+	switch strings.ToLower(strings.TrimSpace(driver)) {
+	case "", DriverLog:
+		return NewLogMailer(), nil
+	case DriverFile:
+		return NewFileMailer(dir), nil
+	}
+
+	return nil, fmt.Errorf("%w: %q (use log ou file)", ErrUnsupportedMailer, driver)
+}
+
+// LogMailer escreve a mensagem no log da aplicação
+type LogMailer struct {
+	Logger *log.Logger
+}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{Logger: log.Default()}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	m.Logger.Printf("e-mail para %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer grava cada mensagem como um arquivo .eml no diretório informado
+type FileMailer struct {
+	Dir string
+}
+
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{Dir: dir}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405.000000000"), sanitize(msg.To))
+
+	return os.WriteFile(filepath.Join(m.Dir, name), []byte(msg.format(now)), 0o600)
+}
+
+func (msg Message) format(date time.Time) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	return b.String()
+}
+
+// sanitize evita que o destinatário gere caminhos fora do diretório
+func sanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+
+		return r
+	}, value)
+}
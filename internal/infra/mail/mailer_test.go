@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	mailer, err := New("", "")
+	assert.NoError(t, err)
+	assert.IsType(t, &LogMailer{}, mailer)
+
+	mailer, err = New("FILE", "mail")
+	assert.NoError(t, err)
+	assert.Equal(t, &FileMailer{Dir: "mail"}, mailer)
+
+	_, err = New("smtp", "")
+	assert.ErrorIs(t, err, ErrUnsupportedMailer)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := NewFileMailer(dir)
+
+	err := mailer.Send(Message{From: "api@localhost", To: "../rafael@gmail.com", Subject: "Teste", Body: "Olá"})
+	assert.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	content, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "To: ../rafael@gmail.com\r\n")
+	assert.Contains(t, string(content), "Subject: Teste\r\n")
+	assert.Contains(t, string(content), "\r\n\r\nOlá")
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := &LogMailer{Logger: log.New(&buf, "", 0)}
+
+	assert.NoError(t, mailer.Send(Message{To: "rafael@gmail.com", Subject: "Teste", Body: "Olá"}))
+	assert.Contains(t, buf.String(), "rafael@gmail.com")
+	assert.Contains(t, buf.String(), "Olá")
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Rótulo das requisições que não casam com nenhuma rota. O caminho cru nunca
+// vira rótulo: cada URL inventada por um scanner seria uma série nova.
+const unmatchedRoute = "unmatched"
+
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodOptions: true,
+}
+
+// Middleware mede as requisições pelo padrão da rota do chi ("/products/{id}").
+// Deve ser registrado no router raiz: a rota é resolvida antes de chamar o
+// handler, para que o gauge de requisições em andamento já tenha o rótulo certo.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.Method
+
+		if !knownMethods[method] {
+			method = "OTHER"
+		}
+
+		route := routePattern(r)
+		inFlight := m.inFlight.WithLabelValues(method, route)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+
+		// Quem não escreve nada responde 200
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.duration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	})
+}
+
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+
+	if rctx == nil || rctx.Routes == nil {
+		return unmatchedRoute
+	}
+
+	path := r.URL.RawPath
+
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	// Um contexto novo, para não mexer no que o chi vai usar no roteamento
+	match := chi.NewRouteContext()
+
+	if !rctx.Routes.Match(match, r.Method, path) {
+		return unmatchedRoute
+	}
+
+	return match.RoutePattern()
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics reúne os coletores da aplicação num registry próprio, então os
+// testes podem criar quantos quiserem sem conflito de nomes.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+
+	queries     *prometheus.HistogramVec
+	queryErrors *prometheus.CounterVec
+
+	tokensIssued  prometheus.Counter
+	loginFailures *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Requisições HTTP atendidas, por rota do chi e status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Tempo para atender uma requisição HTTP, por rota do chi.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Requisições HTTP em andamento, por rota do chi.",
+		}, []string{"method", "route"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Tempo das operações do gorm, por operação e tabela.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_query_errors_total",
+			Help: "Operações do gorm que falharam, sem contar registro não encontrado.",
+		}, []string{"operation", "table"}),
+		tokensIssued: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "auth_tokens_issued_total",
+			Help: "Pares de tokens emitidos no login por senha.",
+		}),
+		loginFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_login_failures_total",
+			Help: "Logins por senha recusados, por motivo.",
+		}, []string{"reason"}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.inFlight,
+		m.queries, m.queryErrors,
+		m.tokensIssued, m.loginFailures,
+	)
+
+	return m
+}
+
+// Handler expõe o registry no formato de texto do Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+func (m *Metrics) TokenIssued() {
+	m.tokensIssued.Inc()
+}
+
+// LoginFailed conta um login recusado; reason deve vir de um conjunto fixo
+func (m *Metrics) LoginFailed(reason string) {
+	m.loginFailures.WithLabelValues(reason).Inc()
+}
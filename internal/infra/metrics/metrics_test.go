@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMiddlewareUsesRoutePattern(t *testing.T) {
+	m := New()
+	router := chi.NewRouter()
+	router.Use(m.Middleware)
+	router.Route("/products", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.inFlight.WithLabelValues("GET", "/products/{id}")))
+			w.WriteHeader(http.StatusNotFound)
+		})
+	})
+	router.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, path := range []string{"/products/1", "/products/2", "/healthz", "/wp-admin.php"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PROPFIND", "/healthz", nil))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/products/{id}", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/healthz", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", unmatchedRoute, "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("OTHER", unmatchedRoute, "405")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.inFlight.WithLabelValues("GET", "/products/{id}")))
+	assert.Equal(t, 4, testutil.CollectAndCount(m.duration))
+}
+
+func TestInstrumentDB(t *testing.T) {
+	m := New()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, m.InstrumentDB(db, "test"))
+
+	type item struct {
+		Id   int
+		Name string
+	}
+
+	assert.NoError(t, db.AutoMigrate(&item{}))
+	assert.NoError(t, db.Create(&item{Name: "a"}).Error)
+
+	var found item
+	assert.ErrorIs(t, db.First(&found, 99).Error, gorm.ErrRecordNotFound)
+	assert.Error(t, db.Table("missing").Create(map[string]interface{}{"name": "x"}).Error)
+
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.queryErrors.WithLabelValues("query", "items")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.queryErrors.WithLabelValues("create", "missing")))
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `db_query_duration_seconds_count{operation="create",table="items"} 1`)
+	assert.Contains(t, body, `db_query_duration_seconds_count{operation="query",table="items"} 1`)
+	assert.Contains(t, body, `go_sql_open_connections{db_name="test"}`)
+}
+
+func TestHandlerServesAuthCounters(t *testing.T) {
+	m := New()
+	m.TokenIssued()
+	m.LoginFailed("invalid_credentials")
+	m.LoginFailed("invalid_credentials")
+
+	body := scrape(t, m)
+	assert.Contains(t, body, "auth_tokens_issued_total 1")
+	assert.Contains(t, body, `auth_login_failures_total{reason="invalid_credentials"} 2`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func scrape(t *testing.T, m *Metrics) string {
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	return w.Body.String()
+}
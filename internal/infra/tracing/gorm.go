@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+type querySpan struct {
+	span      trace.Span
+	operation string
+}
+
+// InstrumentDB abre um span para cada operação do gorm feita com um contexto
+// que já tem span, como o de db.WithContext(r.Context()) num handler. Operações
+// sem span no contexto (jobs, migrations) não geram traces soltos.
+func InstrumentDB(db *gorm.DB, provider trace.TracerProvider) error {
+	tracer := provider.Tracer(scope)
+	system := dbSystem(db.Dialector.Name())
+	cb := db.Callback()
+
+	start := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			ctx := tx.Statement.Context
+
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				return
+			}
+
+			_, span := tracer.Start(ctx, "gorm."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(system, semconv.DBOperation(operation)),
+			)
+			tx.InstanceSet(spanKey, querySpan{span: span, operation: operation})
+		}
+	}
+
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", start("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", endSpan),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", start("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", endSpan),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", start("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", endSpan),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", start("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", endSpan),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", start("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", endSpan),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", start("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", endSpan),
+	)
+}
+
+func endSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(spanKey)
+
+	if !ok {
+		return
+	}
+
+	query := value.(querySpan)
+	span := query.span
+	defer span.End()
+
+	if tx.Statement.Table != "" {
+		span.SetName("gorm." + query.operation + " " + tx.Statement.Table)
+		span.SetAttributes(semconv.DBSQLTable(tx.Statement.Table))
+	}
+
+	// Só o SQL com placeholders; os valores podem ter senhas e e-mails
+	span.SetAttributes(semconv.DBStatement(tx.Statement.SQL.String()))
+
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(tx.Error)
+		span.SetStatus(codes.Error, tx.Error.Error())
+	}
+}
+
+func dbSystem(dialector string) attribute.KeyValue {
+	switch dialector {
+	case "sqlite":
+		return semconv.DBSystemSqlite
+	case "postgres":
+		return semconv.DBSystemPostgreSQL
+	case "mysql":
+		return semconv.DBSystemMySQL
+	default:
+		return semconv.DBSystemKey.String(dialector)
+	}
+}
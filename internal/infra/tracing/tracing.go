@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var ErrUnsupportedExporter = errors.New("exporter de tracing não suportado")
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Nome do instrumentation scope dos spans criados aqui
+const scope = "github.com/rafaelsouzaribeiro/9-API/internal/infra/tracing"
+
+// Propagator lê e escreve o traceparent/tracestate do W3C e o baggage
+var Propagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+type Config struct {
+	Exporter string
+	// URL do coletor OTLP/HTTP, por exemplo http://localhost:4318
+	Endpoint    string
+	SampleRatio float64
+	ServiceName string
+	Version     string
+}
+
+// ValidateExporter confere o nome do exporter sem criá-lo
+func ValidateExporter(name string) error {
+	switch name {
+	case ExporterNone, ExporterOTLP, ExporterStdout:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedExporter, name)
+	}
+}
+
+// NewExporter devolve nil para ExporterNone
+func NewExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, ValidateExporter(cfg.Exporter)
+	}
+}
+
+// NewProvider envia os spans em lote para exporter. Uma requisição que chega
+// com traceparent segue a decisão de quem chamou; as demais são amostradas
+// na proporção SampleRatio.
+func NewProvider(exporter sdktrace.SpanExporter, cfg Config) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(cfg.ServiceName),
+			semconv.ServiceVersion(cfg.Version),
+		)),
+	)
+}
+
+// Setup cria o provider de cfg e o registra como global. Sem exporter, o
+// provider não grava nada, mas o traceparent continua sendo repassado. A
+// função devolvida envia os spans pendentes e deve rodar no encerramento.
+func Setup(ctx context.Context, cfg Config) (trace.TracerProvider, func(context.Context) error, error) {
+	otel.SetTextMapPropagator(Propagator)
+
+	exporter, err := NewExporter(ctx, cfg)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if exporter == nil {
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	}
+
+	provider := NewProvider(exporter, cfg)
+	otel.SetTracerProvider(provider)
+
+	return provider, provider.Shutdown, nil
+}
@@ -0,0 +1,165 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/tracing/tracingtest"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/webservice/handlers"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func setupTestDatabase(t *testing.T, provider trace.TracerProvider) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.Product{}, &entity.User{}, &entity.RefreshToken{}))
+	assert.NoError(t, InstrumentDB(db, provider))
+
+	return db
+}
+
+func spanNamed(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+
+	return nil
+}
+
+func TestTraceSpansRouterAndRepository(t *testing.T) {
+	provider, exporter := tracingtest.NewProvider()
+	db := setupTestDatabase(t, provider)
+	products := database.NewProduct(db)
+
+	router := chi.NewRouter()
+	router.Use(Middleware(provider))
+	router.Get("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, err := products.WithContext(r.Context()).FindById(chi.URLParam(r, "id"))
+		assert.Error(t, err)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/products/abc", nil)
+	r.Header.Set("traceparent", traceparent)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+
+	server := spanNamed(spans, "GET /products/{id}")
+	assert.NotNil(t, server)
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.True(t, server.Parent.IsRemote())
+	assert.Contains(t, server.Attributes, attribute.Int("http.response.status_code", http.StatusNotFound))
+	assert.Contains(t, server.Attributes, attribute.String("http.route", "/products/{id}"))
+	assert.Equal(t, codes.Unset, server.Status.Code)
+
+	query := spanNamed(spans, "gorm.query products")
+	assert.NotNil(t, query)
+	assert.Equal(t, server.SpanContext.SpanID(), query.Parent.SpanID())
+	assert.Contains(t, query.Attributes, attribute.String("db.system", "sqlite"))
+	assert.Contains(t, query.Attributes, attribute.String("db.sql.table", "products"))
+	// Registro não encontrado não é falha do banco
+	assert.Equal(t, codes.Unset, query.Status.Code)
+}
+
+func TestTraceSpansLoginQueries(t *testing.T) {
+	provider, exporter := tracingtest.NewProvider()
+	db := setupTestDatabase(t, provider)
+	users := database.NewUser(db)
+	user, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	assert.NoError(t, users.Create(user))
+
+	login := auth.NewLoginService(users, auth.NewLoginLimiter(3, time.Minute, time.Hour), auth.NewLoginLimiter(10, time.Minute, time.Hour))
+	tokens := auth.NewTokenService(auth.NewHMACKeySet([]byte("secret")), time.Minute, time.Hour, "", "", users, database.NewRefreshToken(db), nil)
+	userHandler := handlers.NewUserHandler(users, tokens, nil, &auth.VerificationService{}, login, nil)
+
+	router := chi.NewRouter()
+	router.Use(Middleware(provider))
+	router.Post("/users/generate_token", userHandler.GetJwt)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/generate_token", strings.NewReader(`{"email":"rafael@gmail.com","password":"rafa2024x"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	spans := exporter.GetSpans()
+	server := spanNamed(spans, "POST /users/generate_token")
+	assert.NotNil(t, server)
+
+	for _, name := range []string{"gorm.query users", "gorm.create refresh_tokens"} {
+		query := spanNamed(spans, name)
+
+		if assert.NotNil(t, query, name) {
+			assert.Equal(t, server.SpanContext.SpanID(), query.Parent.SpanID(), name)
+		}
+	}
+}
+
+func TestTraceMarksServerErrors(t *testing.T) {
+	provider, exporter := tracingtest.NewProvider()
+
+	router := chi.NewRouter()
+	router.Use(Middleware(provider))
+	router.Get("/boom", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/boom", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, codes.Error, spanNamed(spans, "GET /boom").Status.Code)
+	// Sem rota o nome não pode ter o caminho cru
+	assert.NotNil(t, spanNamed(spans, "GET"))
+}
+
+func TestInstrumentDBSkipsQueriesWithoutSpan(t *testing.T) {
+	provider, exporter := tracingtest.NewProvider()
+	db := setupTestDatabase(t, provider)
+
+	_, err := database.NewProduct(db).WithContext(context.Background()).FindById("abc")
+	assert.Error(t, err)
+	assert.Empty(t, exporter.GetSpans())
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "job")
+	assert.Error(t, db.WithContext(ctx).Exec("SELECT * FROM missing").Error)
+	span.End()
+
+	failed := spanNamed(exporter.GetSpans(), "gorm.raw")
+	assert.NotNil(t, failed)
+	assert.Equal(t, codes.Error, failed.Status.Code)
+	assert.Contains(t, failed.Attributes, attribute.String("db.statement", "SELECT * FROM missing"))
+}
+
+func TestSetup(t *testing.T) {
+	provider, shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, span := provider.Tracer("test").Start(context.Background(), "x")
+	assert.False(t, span.SpanContext().IsSampled())
+
+	_, _, err = Setup(context.Background(), Config{Exporter: "zipkin"})
+	assert.ErrorIs(t, err, ErrUnsupportedExporter)
+	assert.NoError(t, ValidateExporter(ExporterOTLP))
+}
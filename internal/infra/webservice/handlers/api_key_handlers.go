@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+)
+
+// CreateAPIKey godoc
+// @Summary      Create an API key
+// @Description  Create a key for machine access to /products, sent in the X-API-Key header. The key acts with the owner's current role and is shown only in this response.
+// @Tags         api keys
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.CreateAPIKeyInput  true  "key name"
+// @Success      201  {object}  dto.CreateAPIKeyOutput
+// @Failure      400  {object}  Error
+// @Failure      401  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      413  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/me/api_keys [post]
+// @Security ApiKeyAuth
+func (u *UserHandlers) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var input dto.CreateAPIKeyInput
+
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	user, ok := u.currentUser(w, r)
+
+	if !ok {
+		return
+	}
+
+	key, plain, err := u.APIKeys.Create(r.Context(), user, input.Name)
+
+	if errors.Is(err, entity.ErrAPIKeyNameIsRequired) {
+		validationError(w, r, err)
+		return
+	}
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.CreateAPIKeyOutput{
+		Id:        key.Id.String(),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Key:       plain,
+		CreatedAt: key.CreatedAt,
+	})
+}
+
+// GetAPIKeys godoc
+// @Summary      List API keys
+// @Description  Active API keys of the token owner, newest first. Only the prefix of each key is shown.
+// @Tags         api keys
+// @Produce      json
+// @Success      200  {array}   entity.APIKey
+// @Failure      401  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/me/api_keys [get]
+// @Security ApiKeyAuth
+func (u *UserHandlers) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+
+	if !ok {
+		return
+	}
+
+	keys, err := u.APIKeys.List(r.Context(), user)
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	if keys == nil {
+		keys = []entity.APIKey{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke an API key
+// @Description  Revoke one of the token owner's API keys. Requests using it are refused right away.
+// @Tags         api keys
+// @Param        id   path      string  true  "api key ID" Format(uuid)
+// @Success      204
+// @Failure      401  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/me/api_keys/{id} [delete]
+// @Security ApiKeyAuth
+func (u *UserHandlers) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+
+	if !ok {
+		return
+	}
+
+	err := u.APIKeys.Revoke(r.Context(), user, chi.URLParam(r, "id"))
+
+	if errors.Is(err, database.ErrNotFound) {
+		notFound(w, r, "API key não encontrada")
+		return
+	}
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
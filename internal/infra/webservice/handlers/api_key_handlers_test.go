@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAPIKeys struct {
+	keys []*entity.APIKey
+}
+
+func (f *fakeAPIKeys) Create(key *entity.APIKey) error {
+	f.keys = append(f.keys, key)
+	return nil
+}
+
+func (f *fakeAPIKeys) FindByHash(hash string) (*entity.APIKey, error) {
+	for _, key := range f.keys {
+		if key.KeyHash == hash {
+			return key, nil
+		}
+	}
+
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeAPIKeys) FindByUser(userId pkg.Id) ([]entity.APIKey, error) {
+	var keys []entity.APIKey
+
+	for _, key := range f.keys {
+		if key.UserId == userId && !key.IsRevoked() {
+			keys = append(keys, *key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (f *fakeAPIKeys) Revoke(userId pkg.Id, id string) error {
+	for _, key := range f.keys {
+		if key.Id.String() == id && key.UserId == userId {
+			now := time.Now()
+			key.RevokedAt = &now
+			return nil
+		}
+	}
+
+	return database.ErrNotFound
+}
+
+func (f *fakeAPIKeys) RevokeUser(userId pkg.Id) error {
+	now := time.Now()
+
+	for _, key := range f.keys {
+		if key.UserId == userId && !key.IsRevoked() {
+			key.RevokedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeAPIKeys) Touch(id pkg.Id, usedAt time.Time) error { return nil }
+
+func (f *fakeAPIKeys) WithContext(ctx context.Context) database.APIKeyInterface {
+	return f
+}
+
+func newAPIKeyHandler(db *fakeUserDB) *UserHandlers {
+	return NewUserHandler(db, nil, nil, noVerification, nil, auth.NewAPIKeyService(&fakeAPIKeys{}, db))
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	db := &fakeUserDB{}
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleEditor)
+	other := newTestUser(t, db, "ana@gmail.com", entity.RoleViewer)
+	handler := newAPIKeyHandler(db)
+
+	r := httptest.NewRequest(http.MethodPost, "/users/me/api_keys", strings.NewReader(`{"name":"importador"}`))
+	w := httptest.NewRecorder()
+	handler.CreateAPIKey(w, withToken(r, user))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created dto.CreateAPIKeyOutput
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+
+	found, err := handler.APIKeys.Authenticate(context.Background(), created.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id, found.Id)
+
+	w = httptest.NewRecorder()
+	handler.GetAPIKeys(w, withToken(httptest.NewRequest(http.MethodGet, "/users/me/api_keys", nil), user))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), created.Key)
+
+	var listed []map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&listed)
+	assert.Len(t, listed, 1)
+	assert.Equal(t, created.Prefix, listed[0]["prefix"])
+	assert.NotContains(t, listed[0], "key_hash")
+
+	// Outro usuário não vê nem revoga a chave
+	w = httptest.NewRecorder()
+	handler.GetAPIKeys(w, withToken(httptest.NewRequest(http.MethodGet, "/users/me/api_keys", nil), other))
+	assert.Equal(t, "[]\n", w.Body.String())
+
+	revoke := func(caller *entity.User) int {
+		r := httptest.NewRequest(http.MethodDelete, "/users/me/api_keys/"+created.Id, nil)
+		w := httptest.NewRecorder()
+		handler.RevokeAPIKey(w, withToken(withURLParam(r, "id", created.Id), caller))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, revoke(other))
+	assert.Equal(t, http.StatusNoContent, revoke(user))
+
+	_, err = handler.APIKeys.Authenticate(context.Background(), created.Key)
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+}
+
+func TestCreateAPIKeyRequiresName(t *testing.T) {
+	db := &fakeUserDB{}
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleEditor)
+
+	for _, name := range []string{`""`, `"   "`} {
+		r := httptest.NewRequest(http.MethodPost, "/users/me/api_keys", strings.NewReader(`{"name":`+name+`}`))
+		w, body := postJSON(newAPIKeyHandler(db).CreateAPIKey, withToken(r, user))
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.Equal(t, "name", body.Details[0].Field, name)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/users/me/api_keys", strings.NewReader(`{"name":"  importador  "}`))
+	w := httptest.NewRecorder()
+	newAPIKeyHandler(db).CreateAPIKey(w, withToken(r, user))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created dto.CreateAPIKeyOutput
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "importador", created.Name)
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+)
+
+// authenticatedUser devolve o "sub" e o "role" do token validado pelo jwtauth.Verifier
+func authenticatedUser(r *http.Request) (id, role string) {
+	_, claims, _ := jwtauth.FromContext(r.Context())
+	id, _ = claims["sub"].(string)
+	role, _ = claims["role"].(string)
+
+	return id, role
+}
+
+// canModifyProduct aplica a regra de que só o dono, ou quem gerencia qualquer produto, altera e apaga
+func canModifyProduct(r *http.Request, product *entity.Product) bool {
+	id, role := authenticatedUser(r)
+
+	return product.IsOwnedBy(id) || entity.RoleHasPermission(role, entity.PermissionProductsManageAny)
+}
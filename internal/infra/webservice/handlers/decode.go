@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Tamanho máximo aceito para corpos JSON
+const maxBodyBytes = 1 << 20
+
+const CodePayloadTooLarge = "payload_too_large"
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Os erros usam o nome do campo no JSON, não o da struct
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+
+		if name == "-" {
+			return ""
+		}
+
+		return name
+	})
+
+	// "   " passa pelo required e pelo min; o conteúdo, sem os espaços, não pode ser vazio
+	v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+
+	return v
+}
+
+// decodeJSON lê o corpo de forma estrita e valida as regras declaradas nas tags
+// "validate" do DTO. Em caso de erro a resposta já foi escrita e devolve false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		decodeError(w, r, err)
+		return false
+	}
+
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		badRequest(w, r, "O corpo deve conter um único objeto JSON")
+		return false
+	}
+
+	if details := validationDetails(dst); len(details) > 0 {
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Dados inválidos", details...)
+		return false
+	}
+
+	return true
+}
+
+func decodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		WriteError(w, r, http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+			fmt.Sprintf("O corpo excede o limite de %d bytes", maxBytesErr.Limit))
+	case errors.As(err, &typeErr):
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Dados inválidos", FieldError{
+			Field:   typeErr.Field,
+			Message: "deve ser do tipo " + typeErr.Type.String(),
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Dados inválidos", FieldError{
+			Field:   field,
+			Message: "campo desconhecido",
+		})
+	case errors.Is(err, io.EOF):
+		badRequest(w, r, "Corpo da requisição vazio")
+	default:
+		badRequest(w, r, "JSON inválido")
+	}
+}
+
+// validationDetails devolve um FieldError por regra violada
+func validationDetails(dst interface{}) []FieldError {
+	err := validate.Struct(dst)
+
+	var errs validator.ValidationErrors
+
+	if !errors.As(err, &errs) {
+		return nil
+	}
+
+	details := make([]FieldError, 0, len(errs))
+
+	for _, fieldErr := range errs {
+		details = append(details, FieldError{
+			Field:   fieldPath(fieldErr),
+			Message: validationMessage(fieldErr),
+		})
+	}
+
+	return details
+}
+
+// fieldPath remove o nome da struct do namespace ("CreateUserInput.email" vira "email")
+func fieldPath(fieldErr validator.FieldError) string {
+	parts := strings.SplitN(fieldErr.Namespace(), ".", 2)
+
+	if len(parts) == 2 {
+		return parts[1]
+	}
+
+	return fieldErr.Field()
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	isString := fieldErr.Kind() == reflect.String
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "obrigatório"
+	case "notblank":
+		return "não pode ficar em branco"
+	case "email":
+		return "e-mail inválido"
+	case "min":
+		if isString {
+			return "deve ter no mínimo " + fieldErr.Param() + " caracteres"
+		}
+
+		return "deve ser no mínimo " + fieldErr.Param()
+	case "max":
+		if isString {
+			return "deve ter no máximo " + fieldErr.Param() + " caracteres"
+		}
+
+		return "deve ser no máximo " + fieldErr.Param()
+	case "gt":
+		return "deve ser maior que " + fieldErr.Param()
+	case "gte":
+		return "deve ser maior ou igual a " + fieldErr.Param()
+	case "lte":
+		return "deve ser menor ou igual a " + fieldErr.Param()
+	case "oneof":
+		return "deve ser um de: " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	}
+
+	return "inválido (" + fieldErr.Tag() + ")"
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func createUser(body string) (*httptest.ResponseRecorder, Error) {
+	handler := NewUserHandler(&fakeUserDB{}, nil, nil, noVerification, nil, nil)
+	r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreateUser(w, r)
+
+	var out Error
+	json.NewDecoder(w.Body).Decode(&out)
+
+	return w, out
+}
+
+func TestCreateUserValid(t *testing.T) {
+	w, _ := createUser(`{"name":"Rafael","email":"rafael@gmail.com","password":"rafa2024x"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestDecodeAggregatesFieldErrors(t *testing.T) {
+	w, body := createUser(`{"name":"","email":"rafael","password":""}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, CodeValidationFailed, body.Code)
+	assert.Equal(t, []FieldError{
+		{Field: "name", Message: "obrigatório"},
+		{Field: "email", Message: "e-mail inválido"},
+		{Field: "password", Message: "obrigatório"},
+	}, body.Details)
+}
+
+func TestDecodeUnknownField(t *testing.T) {
+	w, body := createUser(`{"name":"Rafael","email":"rafael@gmail.com","password":"rafa2024x","admin":true}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []FieldError{{Field: "admin", Message: "campo desconhecido"}}, body.Details)
+}
+
+func TestDecodeTypeMismatch(t *testing.T) {
+	w, body := createUser(`{"name":"Rafael","email":"rafael@gmail.com","password":123456}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "password", body.Details[0].Field)
+	assert.Equal(t, "deve ser do tipo string", body.Details[0].Message)
+}
+
+func TestDecodeRejectsTrailingData(t *testing.T) {
+	w, body := createUser(`{"name":"Rafael","email":"rafael@gmail.com","password":"rafa2024x"}{}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, CodeBadRequest, body.Code)
+}
+
+func TestDecodeEmptyBody(t *testing.T) {
+	w, body := createUser("")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Corpo da requisição vazio", body.Message)
+}
+
+func TestDecodeBodyTooLarge(t *testing.T) {
+	w, body := createUser(`{"name":"` + strings.Repeat("a", maxBodyBytes) + `"}`)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, CodePayloadTooLarge, body.Code)
+}
+
+func TestValidationDetailsNumbers(t *testing.T) {
+	details := validationDetails(&dto.CreateProductInput{Name: "Geladeira", Price: -1})
+
+	assert.Equal(t, []FieldError{{Field: "price", Message: "deve ser maior que 0"}}, details)
+}
+
+func TestCreateUserWeakPassword(t *testing.T) {
+	w, body := createUser(`{"name":"Rafael","email":"rafael@gmail.com","password":"123456"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, CodeValidationFailed, body.Code)
+	assert.Equal(t, "password", body.Details[0].Field)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+)
+
+// Códigos estáveis que os clientes podem usar no lugar da mensagem
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeInternal         = "internal_error"
+	CodeEmailNotVerified = "email_not_verified"
+	CodeTooManyRequests  = "too_many_requests"
+)
+
+const problemContentType = "application/problem+json"
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Code      string       `json:"code" example:"validation_failed"`
+	Message   string       `json:"message" example:"Dados inválidos"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestId string       `json:"request_id,omitempty"`
+}
+
+// Problem segue a RFC 7807, enviado quando o cliente aceita application/problem+json
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestId string       `json:"request_id,omitempty"`
+}
+
+// Relaciona os erros de validação das entidades com o campo do payload
+var fieldErrors = map[error]string{
+	entity.ErrIdIsRequired:    "id",
+	entity.ErrInvalidId:       "id",
+	entity.ErrNameIsRequired:  "name",
+	entity.ErrPriceIsRequired: "price",
+	entity.ErrInvalidPrice:    "price",
+	entity.ErrInvalidRole:     "role",
+
+	entity.ErrPasswordIsRequired:   "password",
+	entity.ErrPasswordTooShort:     "password",
+	entity.ErrPasswordTooLong:      "password",
+	entity.ErrPasswordMissingClass: "password",
+	entity.ErrPasswordTooCommon:    "password",
+}
+
+// isPasswordPolicyError indica que a senha foi recusada pela política
+func isPasswordPolicyError(err error) bool {
+	for target, field := range fieldErrors {
+		if field == "password" && errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WriteError escreve o envelope de erro no formato aceito pelo cliente
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code, message string, details ...FieldError) {
+	requestId := middleware.GetReqID(r.Context())
+
+	if acceptsProblem(r) {
+		w.Header().Set("Content-Type", problemContentType)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(Problem{
+			Type:      "about:blank",
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    message,
+			Instance:  r.URL.Path,
+			Code:      code,
+			Errors:    details,
+			RequestId: requestId,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Error{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestId: requestId,
+	})
+}
+
+func acceptsProblem(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), problemContentType)
+}
+
+func badRequest(w http.ResponseWriter, r *http.Request, message string) {
+	WriteError(w, r, http.StatusBadRequest, CodeBadRequest, message)
+}
+
+func notFound(w http.ResponseWriter, r *http.Request, message string) {
+	WriteError(w, r, http.StatusNotFound, CodeNotFound, message)
+}
+
+// internalError registra a causa no log e não a expõe ao cliente
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("[%s] %s %s: %v", middleware.GetReqID(r.Context()), r.Method, r.URL.Path, err)
+	WriteError(w, r, http.StatusInternalServerError, CodeInternal, "Erro interno")
+}
+
+// validationError converte os erros das entidades em erros por campo
+func validationError(w http.ResponseWriter, r *http.Request, err error) {
+	for target, field := range fieldErrors {
+		if errors.Is(err, target) {
+			WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Dados inválidos", FieldError{
+				Field:   field,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, err.Error())
+}
+
+// NotFound e MethodNotAllowed substituem as respostas em texto do chi
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	notFound(w, r, "Recurso não encontrado")
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Método não permitido")
+}
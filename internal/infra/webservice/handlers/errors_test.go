@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteErrorEnvelope(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+	w := httptest.NewRecorder()
+
+	middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notFound(w, r, "Produto não encontrado")
+	})).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var body Error
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, CodeNotFound, body.Code)
+	assert.Equal(t, "Produto não encontrado", body.Message)
+	assert.NotEmpty(t, body.RequestId)
+}
+
+func TestWriteErrorProblemJson(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/products", nil)
+	r.Header.Set("Accept", "application/problem+json")
+	w := httptest.NewRecorder()
+
+	validationError(w, r, entity.ErrNameIsRequired)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+	var body Problem
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, http.StatusBadRequest, body.Status)
+	assert.Equal(t, "Bad Request", body.Title)
+	assert.Equal(t, "/products", body.Instance)
+	assert.Equal(t, CodeValidationFailed, body.Code)
+	assert.Equal(t, []FieldError{{Field: "name", Message: entity.ErrNameIsRequired.Error()}}, body.Errors)
+}
+
+func TestValidationErrorMapsFields(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/products", nil)
+	w := httptest.NewRecorder()
+
+	validationError(w, r, entity.ErrInvalidPrice)
+
+	var body Error
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, CodeValidationFailed, body.Code)
+	assert.Equal(t, "price", body.Details[0].Field)
+}
+
+func TestInternalErrorHidesCause(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/users", nil)
+	w := httptest.NewRecorder()
+
+	internalError(w, r, assert.AnError)
+
+	var body Error
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, CodeInternal, body.Code)
+	assert.NotContains(t, body.Message, assert.AnError.Error())
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/buildinfo"
+)
+
+// Prazo de cada verificação do /readyz; a sonda desiste antes disso de todo modo
+const readinessTimeout = 2 * time.Second
+
+// HealthCheck é uma dependência que precisa estar de pé para o /readyz responder 200
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthHandler struct {
+	Checks []HealthCheck
+	Build  buildinfo.Info
+}
+
+func NewHealthHandler(build buildinfo.Info, checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{
+		Checks: checks,
+		Build:  build,
+	}
+}
+
+// Healthz godoc
+// @Summary      Liveness probe
+// @Description  Answer 200 while the process is running, without touching the database.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  dto.HealthOutput
+// @Router       /healthz [get]
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, dto.HealthOutput{Status: "ok"})
+}
+
+// Readyz godoc
+// @Summary      Readiness probe
+// @Description  Answer 200 when the database responds and every migration is applied, 503 otherwise. The reason for a failed check is only logged.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  dto.HealthOutput
+// @Failure      503  {object}  dto.HealthOutput
+// @Router       /readyz [get]
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	output := dto.HealthOutput{Status: "ok", Checks: make(map[string]string, len(h.Checks))}
+	status := http.StatusOK
+
+	for _, check := range h.Checks {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		err := check.Check(ctx)
+		cancel()
+
+		if err != nil {
+			// O motivo pode expor detalhes do banco, então fica só no log
+			log.Printf("readyz: %s: %v", check.Name, err)
+			output.Checks[check.Name] = "fail"
+			output.Status = "fail"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+
+		output.Checks[check.Name] = "ok"
+	}
+
+	writeHealth(w, status, output)
+}
+
+// Version godoc
+// @Summary      Build information
+// @Description  Module version, git commit and build time of the running binary, "unknown" when not recorded.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  dto.VersionOutput
+// @Router       /version [get]
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.VersionOutput{
+		Version:   h.Build.Version,
+		Commit:    h.Build.Commit,
+		BuildTime: h.Build.BuildTime,
+		GoVersion: h.Build.GoVersion,
+	})
+}
+
+func writeHealth(w http.ResponseWriter, status int, output dto.HealthOutput) {
+	w.Header().Set("Content-Type", "application/json")
+	// Sondas e balanceadores precisam sempre do estado atual
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(output)
+}
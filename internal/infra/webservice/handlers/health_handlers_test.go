@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/buildinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthz(t *testing.T) {
+	handler := NewHealthHandler(buildinfo.Info{}, HealthCheck{Name: "database", Check: func(context.Context) error {
+		return errors.New("fora do ar")
+	}})
+
+	w := httptest.NewRecorder()
+	handler.Healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestReadyz(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	handler := NewHealthHandler(buildinfo.Info{},
+		HealthCheck{Name: "database", Check: ok},
+		HealthCheck{Name: "migrations", Check: ok},
+	)
+
+	w := httptest.NewRecorder()
+	handler.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"status":"ok","checks":{"database":"ok","migrations":"ok"}}`, w.Body.String())
+}
+
+func TestReadyzFailingCheck(t *testing.T) {
+	handler := NewHealthHandler(buildinfo.Info{},
+		HealthCheck{Name: "database", Check: func(ctx context.Context) error {
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+			return nil
+		}},
+		HealthCheck{Name: "migrations", Check: func(context.Context) error {
+			return errors.New("senha do banco: hunter2")
+		}},
+	)
+
+	w := httptest.NewRecorder()
+	handler.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotContains(t, w.Body.String(), "hunter2")
+
+	var output dto.HealthOutput
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&output))
+	assert.Equal(t, "fail", output.Status)
+	assert.Equal(t, map[string]string{"database": "ok", "migrations": "fail"}, output.Checks)
+}
+
+func TestVersion(t *testing.T) {
+	handler := NewHealthHandler(buildinfo.Info{Version: "v1.4.0", Commit: "3f408a5", BuildTime: "2024-01-02T15:04:05Z", GoVersion: "go1.21.4"})
+
+	w := httptest.NewRecorder()
+	handler.Version(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"version":"v1.4.0","commit":"3f408a5","build_time":"2024-01-02T15:04:05Z","go_version":"go1.21.4"}`, w.Body.String())
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+type KeyPublisher interface {
+	JWKS() (jwk.Set, error)
+}
+
+type JWKSHandler struct {
+	Keys KeyPublisher
+}
+
+func NewJWKSHandler(keys KeyPublisher) *JWKSHandler {
+	return &JWKSHandler{
+		Keys: keys,
+	}
+}
+
+// GetJWKS godoc
+// @Summary      Public signing keys
+// @Description  JSON Web Key Set with the public keys that sign the access tokens, selected by the kid header. Empty while tokens are signed with HS256.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  object
+// @Failure      500  {object}  Error
+// @Router       /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	set, err := h.Keys.JWKS()
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Os serviços podem guardar a resposta, mas devem buscá-la de novo após uma rotação
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(set)
+}
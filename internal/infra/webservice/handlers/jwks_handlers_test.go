@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetJWKS(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	keys, err := auth.NewKeySet("2025",
+		&auth.SigningKey{Id: "2025", Algorithm: jwa.EdDSA, Private: private, Public: public},
+	)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	NewJWKSHandler(keys).GetJWKS(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.NotEmpty(t, w.Header().Get("Cache-Control"))
+
+	var body struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Len(t, body.Keys, 1)
+	assert.Equal(t, "2025", body.Keys[0]["kid"])
+	assert.Equal(t, "OKP", body.Keys[0]["kty"])
+	assert.NotContains(t, body.Keys[0], "d")
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+)
+
+// Cookie que guarda o fluxo selado entre o login e o callback
+const oidcFlowCookie = "oidc_flow"
+
+type OIDCHandler struct {
+	OIDC   *auth.OIDCService
+	Tokens *auth.TokenService
+}
+
+func NewOIDCHandler(oidc *auth.OIDCService, tokens *auth.TokenService) *OIDCHandler {
+	return &OIDCHandler{
+		OIDC:   oidc,
+		Tokens: tokens,
+	}
+}
+
+// Login godoc
+// @Summary      Start an OIDC login
+// @Description  Redirect to the provider's login page using the authorization code flow with PKCE. The state, nonce and code verifier travel in a signed, HttpOnly cookie scoped to the provider's path.
+// @Tags         users
+// @Param        provider   path      string  true  "provider name"
+// @Success      302
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	provider, err := h.OIDC.Provider(chi.URLParam(r, "provider"))
+
+	if err != nil {
+		notFound(w, r, "Provedor não encontrado")
+		return
+	}
+
+	authURL, sealed, err := h.OIDC.Begin(r.Context(), provider.Name)
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	setFlowCookie(w, provider, sealed, int(auth.OIDCFlowTTL.Seconds()))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback godoc
+// @Summary      Finish an OIDC login
+// @Description  Exchange the authorization code and return the same tokens as /users/generate_token. The first login links the identity to the account with the same email, or creates one; the provider must report the email as verified. An unverified local account with that email loses its password, sessions and API keys when claimed.
+// @Tags         users
+// @Produce      json
+// @Param        provider   path      string  true  "provider name"
+// @Param        code       query     string  true  "authorization code"
+// @Param        state      query     string  true  "state sent on login"
+// @Success      200  {object}  dto.GetJWTOutput
+// @Failure      400  {object}  Error
+// @Failure      401  {object}  Error
+// @Failure      403  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	provider, err := h.OIDC.Provider(chi.URLParam(r, "provider"))
+
+	if err != nil {
+		notFound(w, r, "Provedor não encontrado")
+		return
+	}
+
+	// O fluxo é de uso único, qualquer que seja o resultado
+	setFlowCookie(w, provider, "", -1)
+	query := r.URL.Query()
+
+	if reason := query.Get("error"); reason != "" {
+		WriteError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Login recusado pelo provedor: "+reason)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcFlowCookie)
+
+	if err != nil || query.Get("code") == "" || query.Get("state") == "" {
+		badRequest(w, r, "Login OIDC inválido ou expirado")
+		return
+	}
+
+	user, err := h.OIDC.Complete(r.Context(), provider.Name, cookie.Value, query.Get("state"), query.Get("code"))
+
+	switch {
+	case errors.Is(err, auth.ErrInvalidOIDCFlow):
+		badRequest(w, r, "Login OIDC inválido ou expirado")
+		return
+	case errors.Is(err, auth.ErrOIDCEmailNotVerified):
+		WriteError(w, r, http.StatusForbidden, CodeEmailNotVerified, "O provedor não confirmou o e-mail")
+		return
+	case errors.Is(err, auth.ErrOIDCLoginFailed):
+		log.Printf("[%s] %s %s: %v", middleware.GetReqID(r.Context()), r.Method, r.URL.Path, err)
+		WriteError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Login OIDC recusado")
+		return
+	case err != nil:
+		internalError(w, r, err)
+		return
+	}
+
+	tokens, err := h.Tokens.Issue(r.Context(), user)
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	writeTokens(w, tokens)
+}
+
+// setFlowCookie grava o fluxo só para as rotas do provedor; maxAge negativo apaga o cookie
+func setFlowCookie(w http.ResponseWriter, provider *auth.OIDCProvider, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    value,
+		Path:     "/users/oidc/" + provider.Name,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(provider.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth/oidctest"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeIdentities struct {
+	identities []*entity.UserIdentity
+}
+
+func (f *fakeIdentities) Create(identity *entity.UserIdentity) error {
+	f.identities = append(f.identities, identity)
+	return nil
+}
+
+func (f *fakeIdentities) FindBySubject(provider, subject string) (*entity.UserIdentity, error) {
+	for _, identity := range f.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeIdentities) WithContext(ctx context.Context) database.UserIdentityInterface {
+	return f
+}
+
+func newOIDCHandler(t *testing.T, db *fakeUserDB) (*OIDCHandler, *oidctest.Provider) {
+	stub, err := oidctest.NewProvider("api-client", "segredo")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(stub.Close)
+
+	provider := auth.NewOIDCProvider("stub", stub.Issuer(), "api-client", "segredo", "https://api.example.com/users/oidc/stub/callback", nil)
+	sessions := &fakeRefreshTokens{}
+	service := auth.NewOIDCService([]*auth.OIDCProvider{provider}, db, &fakeIdentities{}, sessions, &fakeAPIKeys{}, auth.NewOIDCFlowSigner([]byte("secret")))
+	tokens := &auth.TokenService{JWT: testTokenAuth, AccessTTL: time.Minute, RefreshTokens: sessions}
+
+	return NewOIDCHandler(service, tokens), stub
+}
+
+// startOIDCLogin chama o login e devolve o cookie do fluxo e a URL do provedor
+func startOIDCLogin(t *testing.T, handler *OIDCHandler) (*http.Cookie, string) {
+	r := withURLParam(httptest.NewRequest(http.MethodGet, "/users/oidc/stub/login", nil), "provider", "stub")
+	w := httptest.NewRecorder()
+	handler.Login(w, r)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+
+	return cookies[0], w.Header().Get("Location")
+}
+
+func callback(handler *OIDCHandler, cookie *http.Cookie, callbackURL string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, callbackURL, nil)
+
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	handler.Callback(w, withURLParam(r, "provider", "stub"))
+
+	return w
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	db := &fakeUserDB{}
+	handler, stub := newOIDCHandler(t, db)
+
+	cookie, authURL := startOIDCLogin(t, handler)
+	assert.Equal(t, oidcFlowCookie, cookie.Name)
+	assert.Equal(t, "/users/oidc/stub", cookie.Path)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+	callbackURL, err := stub.Authorize(authURL, map[string]interface{}{"email": "rafael@gmail.com", "email_verified": true})
+	assert.NoError(t, err)
+
+	w := callback(handler, cookie, callbackURL)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var output dto.GetJWTOutput
+	json.NewDecoder(w.Body).Decode(&output)
+	assert.NotEmpty(t, output.AcessToken)
+	assert.NotEmpty(t, output.RefreshToken)
+
+	// O callback apaga o cookie do fluxo
+	assert.Equal(t, -1, w.Result().Cookies()[0].MaxAge)
+
+	user, err := db.FindByEmail("rafael@gmail.com")
+	assert.NoError(t, err)
+	assert.False(t, user.HasPassword())
+}
+
+func TestOIDCCallbackErrors(t *testing.T) {
+	db := &fakeUserDB{}
+	handler, stub := newOIDCHandler(t, db)
+
+	r := withURLParam(httptest.NewRequest(http.MethodGet, "/users/oidc/outro/login", nil), "provider", "outro")
+	w := httptest.NewRecorder()
+	handler.Login(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = callback(handler, nil, "/users/oidc/stub/callback?error=access_denied")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	cookie, authURL := startOIDCLogin(t, handler)
+	callbackURL, _ := stub.Authorize(authURL, map[string]interface{}{"email": "rafael@gmail.com", "email_verified": false})
+
+	// Sem o cookie não há como conferir o state
+	w = callback(handler, nil, callbackURL)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	link, _ := url.Parse(callbackURL)
+	query := link.Query()
+	query.Set("state", "outro")
+	w = callback(handler, cookie, "/users/oidc/stub/callback?"+query.Encode())
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, callbackURL, nil)
+	r.AddCookie(cookie)
+	w, body := postJSON(handler.Callback, withURLParam(r, "provider", "stub"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, CodeEmailNotVerified, body.Code)
+
+	// O code já foi usado e o provedor recusa a troca
+	w = callback(handler, cookie, callbackURL)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
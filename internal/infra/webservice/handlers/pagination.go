@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// positiveIntParam lê um parâmetro inteiro opcional da query string
+func positiveIntParam(r *http.Request, name string, fallback int) (int, *FieldError) {
+	value := r.URL.Query().Get(name)
+
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+
+	if err != nil || n < 1 {
+		return 0, &FieldError{Field: name, Message: "deve ser um inteiro positivo"}
+	}
+
+	return n, nil
+}
+
+// pageParams lê page e limit, limitando o tamanho da página a maxPageLimit
+func pageParams(r *http.Request) (page, limit int, details []FieldError) {
+	page, fieldErr := positiveIntParam(r, "page", 1)
+
+	if fieldErr != nil {
+		details = append(details, *fieldErr)
+	}
+
+	limit, fieldErr = positiveIntParam(r, "limit", defaultPageLimit)
+
+	if fieldErr != nil {
+		details = append(details, *fieldErr)
+	}
+
+	return page, min(limit, maxPageLimit), details
+}
+
+// totalPages arredonda para cima a divisão do total pelo tamanho da página
+func totalPages(total int64, limit int) int {
+	return int((total + int64(limit) - 1) / int64(limit))
+}
+
+// pageLinks escreve o cabeçalho Link da paginação por página e devolve as URLs
+// da próxima e da anterior, vazias quando não existem
+func pageLinks(w http.ResponseWriter, r *http.Request, page, totalPages int) (next, prev string) {
+	links := map[string]string{
+		"first": pageURL(r, map[string]string{"page": "1"}),
+		"last":  pageURL(r, map[string]string{"page": strconv.Itoa(max(totalPages, 1))}),
+	}
+
+	if page < totalPages {
+		next = pageURL(r, map[string]string{"page": strconv.Itoa(page + 1)})
+		links["next"] = next
+	}
+
+	if page > 1 {
+		prev = pageURL(r, map[string]string{"page": strconv.Itoa(min(page-1, max(totalPages, 1)))})
+		links["prev"] = prev
+	}
+
+	setLinkHeader(w, []string{"first", "prev", "next", "last"}, links)
+
+	return next, prev
+}
+
+// pageURL devolve a URL da requisição atual trocando os parâmetros informados
+func pageURL(r *http.Request, params map[string]string) string {
+	query := r.URL.Query()
+
+	for key, value := range params {
+		if value == "" {
+			query.Del(key)
+			continue
+		}
+
+		query.Set(key, value)
+	}
+
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// setLinkHeader escreve o cabeçalho Link (RFC 8288) na ordem recebida
+func setLinkHeader(w http.ResponseWriter, rels []string, links map[string]string) {
+	var values []string
+
+	for _, rel := range rels {
+		if link, ok := links[rel]; ok {
+			values = append(values, fmt.Sprintf(`<%s>; rel="%s"`, link, rel))
+		}
+	}
+
+	if len(values) > 0 {
+		w.Header().Set("Link", strings.Join(values, ", "))
+	}
+}
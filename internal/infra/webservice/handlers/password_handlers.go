@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+)
+
+// ChangePassword godoc
+// @Summary      Change the password
+// @Description  Change the password of the token owner. Requires the current password and ends every session; the access token in use stays valid until it expires.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.ChangePasswordInput  true  "current and new password"
+// @Success      204
+// @Failure      400  {object}  Error
+// @Failure      401  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      413  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/me/password [post]
+// @Security ApiKeyAuth
+func (u *UserHandlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var input dto.ChangePasswordInput
+
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	user, ok := u.currentUser(w, r)
+
+	if !ok {
+		return
+	}
+
+	err := u.Passwords.Change(r.Context(), user, input.CurrentPassword, input.NewPassword)
+
+	if errors.Is(err, auth.ErrWrongPassword) {
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Dados inválidos", FieldError{
+			Field:   "current_password",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if isPasswordPolicyError(err) {
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Dados inválidos", FieldError{
+			Field:   "new_password",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestPasswordReset godoc
+// @Summary      Request a password reset
+// @Description  Email a single-use token to reset the password. The answer is the same, and as fast, whether the email is registered or not. Too many requests for the same email or from the same IP are refused for a while.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.PasswordResetRequestInput  true  "account email"
+// @Success      202
+// @Failure      400  {object}  Error
+// @Failure      413  {object}  Error
+// @Failure      429  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/password/reset [post]
+func (u *UserHandlers) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var input dto.PasswordResetRequestInput
+
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	err := u.Passwords.RequestReset(r.Context(), input.Email, clientIP(r))
+
+	var locked *auth.LockedError
+
+	if errors.As(err, &locked) {
+		tooManyRequests(w, r, locked)
+		return
+	}
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ConfirmPasswordReset godoc
+// @Summary      Reset the password
+// @Description  Set a new password with the token received by email. The token works once and every session is ended.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.PasswordResetConfirmInput  true  "token and new password"
+// @Success      204
+// @Failure      400  {object}  Error
+// @Failure      413  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/password/reset/confirm [post]
+func (u *UserHandlers) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var input dto.PasswordResetConfirmInput
+
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	err := u.Passwords.Reset(r.Context(), input.Token, input.Password)
+
+	if errors.Is(err, auth.ErrInvalidResetToken) {
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Dados inválidos", FieldError{
+			Field:   "token",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if isPasswordPolicyError(err) {
+		validationError(w, r, err)
+		return
+	}
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
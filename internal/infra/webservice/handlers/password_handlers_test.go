@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/mail"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRefreshTokens struct {
+	revokedUsers []pkg.Id
+}
+
+func (f *fakeRefreshTokens) Create(token *entity.RefreshToken) error { return nil }
+
+func (f *fakeRefreshTokens) FindByHash(hash string) (*entity.RefreshToken, error) {
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeRefreshTokens) Rotate(current, next *entity.RefreshToken) error { return nil }
+
+func (f *fakeRefreshTokens) RevokeFamily(familyId pkg.Id) error { return nil }
+
+func (f *fakeRefreshTokens) RevokeUser(userId pkg.Id) error {
+	f.revokedUsers = append(f.revokedUsers, userId)
+	return nil
+}
+
+func (f *fakeRefreshTokens) WithContext(ctx context.Context) database.RefreshTokenInterface {
+	return f
+}
+
+type fakeResetTokens struct {
+	tokens []*entity.PasswordResetToken
+}
+
+func (f *fakeResetTokens) Create(token *entity.PasswordResetToken) error {
+	f.tokens = append(f.tokens, token)
+	return nil
+}
+
+func (f *fakeResetTokens) FindByHash(hash string) (*entity.PasswordResetToken, error) {
+	for _, token := range f.tokens {
+		if token.TokenHash == hash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeResetTokens) Use(token *entity.PasswordResetToken) error {
+	for _, stored := range f.tokens {
+		if stored.Id == token.Id {
+			if stored.IsUsed() {
+				return database.ErrTokenAlreadyUsed
+			}
+
+			now := time.Now()
+			stored.UsedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeResetTokens) WithContext(ctx context.Context) database.PasswordResetTokenInterface {
+	return f
+}
+
+type fakeMailer struct {
+	sent []mail.Message
+}
+
+func (m *fakeMailer) Send(msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func newPasswordHandler(db *fakeUserDB) (*UserHandlers, *fakeRefreshTokens, *fakeMailer) {
+	sessions := &fakeRefreshTokens{}
+	mailer := &fakeMailer{}
+	passwords := auth.NewPasswordService(db, &fakeResetTokens{}, sessions, mailer, "api@localhost", time.Hour, "")
+
+	return NewUserHandler(db, nil, passwords, noVerification, nil, nil), sessions, mailer
+}
+
+func postJSON(handler http.HandlerFunc, r *http.Request) (*httptest.ResponseRecorder, Error) {
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	var body Error
+	json.NewDecoder(w.Body).Decode(&body)
+
+	return w, body
+}
+
+func TestChangePassword(t *testing.T) {
+	db := &fakeUserDB{}
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler, sessions, _ := newPasswordHandler(db)
+
+	r := httptest.NewRequest(http.MethodPost, "/users/me/password", strings.NewReader(`{"current_password":"errada","new_password":"novasenha1"}`))
+	w, body := postJSON(handler.ChangePassword, withToken(r, user))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "current_password", body.Details[0].Field)
+
+	r = httptest.NewRequest(http.MethodPost, "/users/me/password", strings.NewReader(`{"current_password":"rafa2024x","new_password":"novasenha1"}`))
+	w, _ = postJSON(handler.ChangePassword, withToken(r, user))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []pkg.Id{user.Id}, sessions.revokedUsers)
+
+	found, _ := db.FindById(user.Id.String())
+	assert.True(t, found.ValidatePassword("novasenha1"))
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	db := &fakeUserDB{}
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler, _, mailer := newPasswordHandler(db)
+
+	// E-mail desconhecido recebe a mesma resposta, sem envio
+	r := httptest.NewRequest(http.MethodPost, "/users/password/reset", strings.NewReader(`{"email":"ninguem@gmail.com"}`))
+	w, _ := postJSON(handler.RequestPasswordReset, r)
+	handler.Passwords.Wait()
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, mailer.sent)
+
+	r = httptest.NewRequest(http.MethodPost, "/users/password/reset", strings.NewReader(`{"email":"rafael@gmail.com"}`))
+	w, _ = postJSON(handler.RequestPasswordReset, r)
+	handler.Passwords.Wait()
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Len(t, mailer.sent, 1)
+
+	token := strings.Split(mailer.sent[0].Body, "\n")[2]
+	confirm := `{"token":"` + token + `","password":"novasenha1"}`
+
+	r = httptest.NewRequest(http.MethodPost, "/users/password/reset/confirm", strings.NewReader(confirm))
+	w, _ = postJSON(handler.ConfirmPasswordReset, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	found, _ := db.FindById(user.Id.String())
+	assert.True(t, found.ValidatePassword("novasenha1"))
+
+	r = httptest.NewRequest(http.MethodPost, "/users/password/reset/confirm", strings.NewReader(confirm))
+	w, body := postJSON(handler.ConfirmPasswordReset, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "token", body.Details[0].Field)
+}
+
+func TestPasswordPolicyErrors(t *testing.T) {
+	db := &fakeUserDB{}
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler, sessions, mailer := newPasswordHandler(db)
+
+	r := httptest.NewRequest(http.MethodPost, "/users/me/password", strings.NewReader(`{"current_password":"rafa2024x","new_password":"curta1"}`))
+	w, body := postJSON(handler.ChangePassword, withToken(r, user))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, CodeValidationFailed, body.Code)
+	assert.Equal(t, "new_password", body.Details[0].Field)
+	assert.Empty(t, sessions.revokedUsers)
+
+	r = httptest.NewRequest(http.MethodPost, "/users/password/reset", strings.NewReader(`{"email":"rafael@gmail.com"}`))
+	postJSON(handler.RequestPasswordReset, r)
+	handler.Passwords.Wait()
+	token := strings.Split(mailer.sent[0].Body, "\n")[2]
+
+	r = httptest.NewRequest(http.MethodPost, "/users/password/reset/confirm", strings.NewReader(`{"token":"`+token+`","password":"password123"}`))
+	w, body = postJSON(handler.ConfirmPasswordReset, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "password", body.Details[0].Field)
+
+	// A senha recusada não gasta o token
+	r = httptest.NewRequest(http.MethodPost, "/users/password/reset/confirm", strings.NewReader(`{"token":"`+token+`","password":"novasenha1"}`))
+	w, _ = postJSON(handler.ConfirmPasswordReset, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
@@ -2,8 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
@@ -24,15 +25,20 @@ func NewProductHandler(db database.ProductInterface) *ProductHandler {
 
 // Create Product godoc
 // @Summary      Create product
-// @Description  Create products
+// @Description  Create products owned by the authenticated user
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        request     body      dto.CreateProductInput  true  "product request"
 // @Success      201
+// @Failure      400         {object}  Error
+// @Failure      401         {object}  Error
+// @Failure      403         {object}  Error
+// @Failure      413         {object}  Error
 // @Failure      500         {object}  Error
 // @Router       /products [post]
 // @Security ApiKeyAuth
+// @Security ApiKeyHeader
 func (p *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	// if r.Method != http.MethodPost {
 	// 	http.Error(w, "Method not allow", http.StatusMethodNotAllowed)
@@ -41,23 +47,29 @@ func (p *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 
 	var product dto.CreateProductInput
 
-	err := json.NewDecoder(r.Body).Decode(&product)
-
-	if err != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	if !decodeJSON(w, r, &product) {
 		return
 	}
 
 	ps, errs := entity.NewProduct(product.Name, product.Price)
 
 	if errs != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		validationError(w, r, errs)
 		return
 	}
-	err = p.ProductDB.Create(ps)
+
+	userId, _ := authenticatedUser(r)
+	ownerId, err := pkg.ParseId(userId)
 
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Token sem usuário")
+		return
+	}
+
+	ps.SetOwner(ownerId)
+
+	if err := p.ProductDB.WithContext(r.Context()).Create(ps); err != nil {
+		internalError(w, r, err)
 		return
 	}
 
@@ -74,21 +86,24 @@ func (p *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        id   path      string  true  "product ID" Format(uuid)
 // @Success      200  {object}  entity.Product
-// @Failure      404
+// @Failure      400  {object}  Error
+// @Failure      401  {object}  Error
+// @Failure      404  {object}  Error
 // @Failure      500  {object}  Error
 // @Router       /products/{id} [get]
 // @Security ApiKeyAuth
+// @Security ApiKeyHeader
 func (p *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	if id == "" {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		badRequest(w, r, "Id obrigatório")
 		return
 	}
-	product, err := p.ProductDB.FindById(id)
+	product, err := p.ProductDB.WithContext(r.Context()).FindById(id)
 
 	if err != nil {
-		http.Error(w, "Not Found", http.StatusNotFound)
+		p.findError(w, r, err)
 		return
 	}
 
@@ -99,51 +114,60 @@ func (p *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 
 // UpdateProduct godoc
 // @Summary      Update a product
-// @Description  Update a product
+// @Description  Update a product. Only its owner or an admin can update it.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        id        	path      string                  true  "product ID" Format(uuid)
 // @Param        request    body      dto.CreateProductInput  true  "product request"
 // @Success      200
-// @Failure      404
+// @Failure      400       {object}  Error
+// @Failure      401       {object}  Error
+// @Failure      403       {object}  Error
+// @Failure      404       {object}  Error
+// @Failure      413       {object}  Error
 // @Failure      500       {object}  Error
 // @Router       /products/{id} [put]
 // @Security ApiKeyAuth
+// @Security ApiKeyHeader
 func (p *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	if id == "" {
-		http.Error(w, "Not Found", http.StatusNotFound)
+		notFound(w, r, "Produto não encontrado")
 		return
 	}
 
-	var product entity.Product
-	err := json.NewDecoder(r.Body).Decode(&product)
+	var input dto.CreateProductInput
 
-	if err != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	if !decodeJSON(w, r, &input) {
 		return
 	}
 
-	product.Id, err = pkg.ParseId(id)
+	product, err := p.ProductDB.WithContext(r.Context()).FindById(id)
 
 	if err != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		p.findError(w, r, err)
+		return
+	}
+
+	if !canModifyProduct(r, product) {
+		forbiddenProduct(w, r)
 		return
 	}
 
-	_, err = p.ProductDB.FindById(id)
+	product.Name = strings.TrimSpace(input.Name)
+	product.Price = input.Price
 
-	if err != nil {
-		http.Error(w, "Not Found", http.StatusNotFound)
+	if err := product.Validate(); err != nil {
+		validationError(w, r, err)
 		return
 	}
 
-	err = p.ProductDB.Update(&product)
+	err = p.ProductDB.WithContext(r.Context()).Update(product)
 
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		internalError(w, r, err)
 		return
 	}
 
@@ -155,35 +179,44 @@ func (p *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 
 // DeleteProduct godoc
 // @Summary      Delete a product
-// @Description  Delete a product
+// @Description  Delete a product. Only its owner or an admin can delete it.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        id        path     string   true  "product ID" Format(uuid)
 // @Success      200
-// @Failure      404
+// @Failure      400       {object}  Error
+// @Failure      401       {object}  Error
+// @Failure      403       {object}  Error
+// @Failure      404       {object}  Error
 // @Failure      500       {object}  Error
 // @Router       /products/{id} [delete]
 // @Security ApiKeyAuth
+// @Security ApiKeyHeader
 func (u *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	if id == "" {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		badRequest(w, r, "Id obrigatório")
 		return
 	}
 
-	_, err := u.ProductDB.FindById(id)
+	product, err := u.ProductDB.WithContext(r.Context()).FindById(id)
 
 	if err != nil {
-		http.Error(w, "Not Found", http.StatusNotFound)
+		u.findError(w, r, err)
+		return
+	}
+
+	if !canModifyProduct(r, product) {
+		forbiddenProduct(w, r)
 		return
 	}
 
-	err = u.ProductDB.Delete(id)
+	err = u.ProductDB.WithContext(r.Context()).Delete(id)
 
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		internalError(w, r, err)
 		return
 	}
 
@@ -194,43 +227,112 @@ func (u *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 
 // ListAccounts godoc
 // @Summary      List products
-// @Description  get all products
+// @Description  get products page by page, or by cursor when the cursor parameter is present (use an empty cursor for the first page)
 // @Tags         products
 // @Accept       json
 // @Produce      json
-// @Param        page      query     string  false  "page number"
-// @Param        limit     query     string  false  "limit"
-// @Success      200       {array}   entity.Product
-// @Failure      404       {object}  Error
-// @Failure      500       {object}  Error
+// @Param        page          query     int     false  "page number"  default(1)
+// @Param        limit         query     int     false  "items per page (max 100)"  default(20)
+// @Param        sort          query     string  false  "sort direction"  Enums(asc, desc)
+// @Param        sort_by       query     string  false  "sort field"  Enums(created_at, name, price)
+// @Param        cursor        query     string  false  "opaque cursor returned in next_cursor"
+// @Param        name          query     string  false  "case-insensitive name substring"
+// @Param        owner_id      query     string  false  "owner user ID, or me for the authenticated user"
+// @Param        min_price     query     number  false  "minimum price"
+// @Param        max_price     query     number  false  "maximum price"
+// @Param        created_from  query     string  false  "created at or after (2006-01-02 or RFC 3339)"
+// @Param        created_to    query     string  false  "created at or before (2006-01-02 or RFC 3339)"
+// @Success      200           {object}  dto.ProductListOutput
+// @Header       200           {string}  Link  "first, prev, next and last page links"
+// @Failure      400           {object}  Error
+// @Failure      401           {object}  Error
+// @Failure      500           {object}  Error
 // @Router       /products [get]
 // @Security ApiKeyAuth
+// @Security ApiKeyHeader
 func (u *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
-	page := r.URL.Query().Get("page")
-	limit := r.URL.Query().Get("limit")
+	query, details := parseProductQuery(r)
 
-	pageInt, err := strconv.Atoi(page)
+	if len(details) > 0 {
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Parâmetros inválidos", details...)
+		return
+	}
 
-	if err != nil {
-		pageInt = 0
+	if r.URL.Query().Has("cursor") {
+		u.getProductsByCursor(w, r, query)
+		return
 	}
 
-	limitInt, err := strconv.Atoi(limit)
+	products, total, errs := u.ProductDB.WithContext(r.Context()).FindAll(query)
 
-	if err != nil {
-		limitInt = 0
+	if errs != nil {
+		internalError(w, r, errs)
+		return
 	}
 
-	sort := r.URL.Query().Get("sort")
-	products, errs := u.ProductDB.FindAll(pageInt, limitInt, sort)
+	output := dto.ProductListOutput{
+		Items:      products,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		Total:      total,
+		TotalPages: totalPages(total, query.Limit),
+	}
 
-	if errs != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	output.Next, output.Prev = pageLinks(w, r, output.Page, output.TotalPages)
+	writeProductList(w, output)
+}
+
+func (u *ProductHandler) getProductsByCursor(w http.ResponseWriter, r *http.Request, query database.ProductQuery) {
+	products, next, total, err := u.ProductDB.WithContext(r.Context()).FindAfter(query)
+
+	if errors.Is(err, database.ErrInvalidCursor) {
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Parâmetros inválidos", FieldError{
+			Field:   "cursor",
+			Message: err.Error(),
+		})
 		return
 	}
 
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	output := dto.ProductListOutput{
+		Items: products,
+		Limit: query.Limit,
+		Total: total,
+	}
+
+	if next != nil {
+		output.NextCursor = next.Encode()
+		output.Next = pageURL(r, map[string]string{"cursor": output.NextCursor, "page": ""})
+		setLinkHeader(w, []string{"next"}, map[string]string{"next": output.Next})
+	}
+
+	writeProductList(w, output)
+}
+
+func writeProductList(w http.ResponseWriter, output dto.ProductListOutput) {
+	if output.Items == nil {
+		output.Items = []entity.Product{}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(products)
+	json.NewEncoder(w).Encode(output)
+}
+
+func forbiddenProduct(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, http.StatusForbidden, CodeForbidden, "Apenas o dono ou um administrador pode alterar este produto")
+}
+
+// findError diferencia produto inexistente de falha no banco
+func (p *ProductHandler) findError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, database.ErrNotFound) {
+		notFound(w, r, "Produto não encontrado")
+		return
+	}
 
+	internalError(w, r, err)
 }
@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProductDB guarda os produtos em memória, na ordem de criação
+type fakeProductDB struct {
+	products []entity.Product
+}
+
+func newFakeProductDB(n int) *fakeProductDB {
+	fake := &fakeProductDB{}
+	now := time.Now()
+
+	for i := 1; i <= n; i++ {
+		product, _ := entity.NewProduct(fmt.Sprintf("Product %d", i), float64(i))
+		product.CreatedAt = now.Add(time.Duration(i) * time.Second)
+		fake.products = append(fake.products, *product)
+	}
+
+	return fake
+}
+
+func (f *fakeProductDB) Create(product *entity.Product) error {
+	f.products = append(f.products, *product)
+	return nil
+}
+
+// query aplica filtros e ordenação do ProductQuery como o banco faria
+func (f *fakeProductDB) query(query database.ProductQuery) []entity.Product {
+	var products []entity.Product
+
+	for _, product := range f.products {
+		if query.Matches(product) {
+			products = append(products, product)
+		}
+	}
+
+	sort.SliceStable(products, func(i, j int) bool {
+		var less bool
+
+		switch query.SortBy {
+		case database.SortByName:
+			less = products[i].Name < products[j].Name
+		case database.SortByPrice:
+			less = products[i].Price < products[j].Price
+		default:
+			less = products[i].CreatedAt.Before(products[j].CreatedAt)
+		}
+
+		if query.Sort == "desc" {
+			return !less
+		}
+
+		return less
+	})
+
+	return products
+}
+
+func (f *fakeProductDB) FindAll(query database.ProductQuery) ([]entity.Product, int64, error) {
+	products := f.query(query)
+	total := int64(len(products))
+
+	if query.Page == 0 || query.Limit == 0 {
+		return products, total, nil
+	}
+
+	start := min((query.Page-1)*query.Limit, len(products))
+	end := min(start+query.Limit, len(products))
+
+	return products[start:end], total, nil
+}
+
+func (f *fakeProductDB) WithContext(ctx context.Context) database.ProductInterface {
+	return f
+}
+
+func (f *fakeProductDB) FindAfter(query database.ProductQuery) ([]entity.Product, *database.Cursor, int64, error) {
+	products := f.query(query)
+	start := 0
+
+	if query.Cursor != nil {
+		for i, product := range products {
+			if product.Id.String() == query.Cursor.Id {
+				start = i + 1
+			}
+		}
+	}
+
+	end := min(start+query.Limit, len(products))
+	page := products[start:end]
+
+	if end == len(products) {
+		return page, nil, int64(len(products)), nil
+	}
+
+	return page, database.CursorFor(page[len(page)-1], query.SortBy), int64(len(products)), nil
+}
+
+func (f *fakeProductDB) FindById(id string) (*entity.Product, error) {
+	for i := range f.products {
+		if f.products[i].Id.String() == id {
+			product := f.products[i]
+			return &product, nil
+		}
+	}
+
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeProductDB) Update(product *entity.Product) error {
+	for i := range f.products {
+		if f.products[i].Id == product.Id {
+			f.products[i] = *product
+			return nil
+		}
+	}
+
+	return database.ErrNotFound
+}
+
+func (f *fakeProductDB) Delete(id string) error {
+	for i := range f.products {
+		if f.products[i].Id.String() == id {
+			f.products = append(f.products[:i], f.products[i+1:]...)
+			return nil
+		}
+	}
+
+	return database.ErrNotFound
+}
+
+func getProducts(handler *ProductHandler, target string) (*httptest.ResponseRecorder, dto.ProductListOutput) {
+	w := httptest.NewRecorder()
+	handler.GetProducts(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+	var output dto.ProductListOutput
+	json.NewDecoder(w.Body).Decode(&output)
+
+	return w, output
+}
+
+func TestGetProductsEnvelope(t *testing.T) {
+	handler := NewProductHandler(newFakeProductDB(23))
+
+	w, output := getProducts(handler, "/products?page=2&limit=10")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, output.Items, 10)
+	assert.Equal(t, "Product 11", output.Items[0].Name)
+	assert.Equal(t, 2, output.Page)
+	assert.Equal(t, 10, output.Limit)
+	assert.Equal(t, int64(23), output.Total)
+	assert.Equal(t, 3, output.TotalPages)
+	assert.Equal(t, "/products?limit=10&page=3", output.Next)
+	assert.Equal(t, "/products?limit=10&page=1", output.Prev)
+	assert.Equal(t,
+		`</products?limit=10&page=1>; rel="first", </products?limit=10&page=1>; rel="prev", </products?limit=10&page=3>; rel="next", </products?limit=10&page=3>; rel="last"`,
+		w.Header().Get("Link"))
+
+	_, output = getProducts(handler, "/products?page=3&limit=10")
+	assert.Len(t, output.Items, 3)
+	assert.Empty(t, output.Next)
+}
+
+func TestGetProductsDefaults(t *testing.T) {
+	handler := NewProductHandler(newFakeProductDB(0))
+
+	w, output := getProducts(handler, "/products?limit=1000")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, output.Items)
+	assert.Equal(t, 1, output.Page)
+	assert.Equal(t, maxPageLimit, output.Limit)
+	assert.Equal(t, int64(0), output.Total)
+}
+
+func TestGetProductsInvalidParams(t *testing.T) {
+	handler := NewProductHandler(newFakeProductDB(1))
+
+	w := httptest.NewRecorder()
+	handler.GetProducts(w, httptest.NewRequest(http.MethodGet, "/products?page=0&limit=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var body Error
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Len(t, body.Details, 2)
+
+	w = httptest.NewRecorder()
+	handler.GetProducts(w, httptest.NewRequest(http.MethodGet, "/products?cursor=invalid", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetProductsCursor(t *testing.T) {
+	handler := NewProductHandler(newFakeProductDB(5))
+
+	_, output := getProducts(handler, "/products?cursor=&limit=2")
+	assert.Len(t, output.Items, 2)
+	assert.Zero(t, output.Page)
+	assert.NotEmpty(t, output.NextCursor)
+
+	var names []string
+
+	for output.NextCursor != "" {
+		_, output = getProducts(handler, "/products?limit=2&cursor="+output.NextCursor)
+
+		for _, product := range output.Items {
+			names = append(names, product.Name)
+		}
+	}
+
+	assert.Equal(t, []string{"Product 3", "Product 4", "Product 5"}, names)
+}
+
+func TestGetProductsFilters(t *testing.T) {
+	handler := NewProductHandler(newFakeProductDB(20))
+
+	w, output := getProducts(handler, "/products?name=product%201&min_price=12&sort_by=price&sort=desc")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(8), output.Total)
+	assert.Equal(t, "Product 19", output.Items[0].Name)
+	assert.Equal(t, "Product 12", output.Items[7].Name)
+
+	_, output = getProducts(handler, "/products?max_price=5&limit=2")
+	assert.Equal(t, int64(5), output.Total)
+	assert.Equal(t, "/products?limit=2&max_price=5&page=2", output.Next)
+}
+
+func TestGetProductsInvalidFilters(t *testing.T) {
+	handler := NewProductHandler(newFakeProductDB(1))
+
+	w := httptest.NewRecorder()
+	handler.GetProducts(w, httptest.NewRequest(http.MethodGet, "/products?min_price=10&max_price=5&sort_by=owner&created_from=ontem", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var body Error
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+
+	var fields []string
+
+	for _, detail := range body.Details {
+		fields = append(fields, detail.Field)
+	}
+
+	assert.ElementsMatch(t, []string{"max_price", "sort_by", "created_from"}, fields)
+}
+
+func productRequest(method, target, body string, user *entity.User, id string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+
+	if id != "" {
+		r = withURLParam(r, "id", id)
+	}
+
+	return withToken(r, user)
+}
+
+func TestCreateProductRecordsOwner(t *testing.T) {
+	users := &fakeUserDB{}
+	editor := newTestUser(t, users, "editor@gmail.com", entity.RoleEditor)
+	db := newFakeProductDB(0)
+	handler := NewProductHandler(db)
+
+	w := httptest.NewRecorder()
+	handler.CreateProduct(w, productRequest(http.MethodPost, "/products", `{"name":"Geladeira","price":10}`, editor, ""))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Len(t, db.products, 1)
+	assert.True(t, db.products[0].IsOwnedBy(editor.Id.String()))
+
+	_, output := getProducts(handler, "/products?owner_id=e0f3c1f4-2b9a-4a3e-9a57-5b8f9d6a1c2e")
+	assert.Empty(t, output.Items)
+
+	w = httptest.NewRecorder()
+	handler.GetProducts(w, productRequest(http.MethodGet, "/products?owner_id=me", "", editor, ""))
+
+	var mine dto.ProductListOutput
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&mine))
+	assert.Len(t, mine.Items, 1)
+}
+
+func TestProductRejectsBlankName(t *testing.T) {
+	users := &fakeUserDB{}
+	editor := newTestUser(t, users, "editor@gmail.com", entity.RoleEditor)
+	db := newFakeProductDB(1)
+	db.products[0].SetOwner(editor.Id)
+	id := db.products[0].Id.String()
+	handler := NewProductHandler(db)
+
+	w := httptest.NewRecorder()
+	handler.CreateProduct(w, productRequest(http.MethodPost, "/products", `{"name":"   ","price":10}`, editor, ""))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, db.products, 1)
+
+	w = httptest.NewRecorder()
+	handler.UpdateProduct(w, productRequest(http.MethodPut, "/products/"+id, `{"name":"   ","price":5}`, editor, id))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Product 1", db.products[0].Name)
+
+	w = httptest.NewRecorder()
+	handler.CreateProduct(w, productRequest(http.MethodPost, "/products", `{"name":"  Geladeira  ","price":10}`, editor, ""))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "Geladeira", db.products[1].Name)
+
+	w = httptest.NewRecorder()
+	handler.UpdateProduct(w, productRequest(http.MethodPut, "/products/"+id, `{"name":"  Fogão  ","price":5}`, editor, id))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Fogão", db.products[0].Name)
+}
+
+func TestUpdateProductOwnership(t *testing.T) {
+	users := &fakeUserDB{}
+	owner := newTestUser(t, users, "owner@gmail.com", entity.RoleEditor)
+	other := newTestUser(t, users, "other@gmail.com", entity.RoleEditor)
+	admin := newTestUser(t, users, "admin@gmail.com", entity.RoleAdmin)
+
+	db := newFakeProductDB(1)
+	db.products[0].SetOwner(owner.Id)
+	id := db.products[0].Id.String()
+	handler := NewProductHandler(db)
+
+	w := httptest.NewRecorder()
+	handler.UpdateProduct(w, productRequest(http.MethodPut, "/products/"+id, `{"name":"Outro","price":5}`, other, id))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "Product 1", db.products[0].Name)
+
+	w = httptest.NewRecorder()
+	handler.UpdateProduct(w, productRequest(http.MethodPut, "/products/"+id, `{"name":"Do dono","price":5}`, owner, id))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Do dono", db.products[0].Name)
+
+	w = httptest.NewRecorder()
+	handler.UpdateProduct(w, productRequest(http.MethodPut, "/products/"+id, `{"name":"Do admin","price":5}`, admin, id))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Do admin", db.products[0].Name)
+	assert.True(t, db.products[0].IsOwnedBy(owner.Id.String()))
+}
+
+func TestDeleteProductOwnership(t *testing.T) {
+	users := &fakeUserDB{}
+	owner := newTestUser(t, users, "owner@gmail.com", entity.RoleEditor)
+	other := newTestUser(t, users, "other@gmail.com", entity.RoleEditor)
+	admin := newTestUser(t, users, "admin@gmail.com", entity.RoleAdmin)
+
+	// O primeiro produto não tem dono, como os criados antes da migration
+	db := newFakeProductDB(2)
+	db.products[1].SetOwner(owner.Id)
+	legacy, owned := db.products[0].Id.String(), db.products[1].Id.String()
+	handler := NewProductHandler(db)
+
+	w := httptest.NewRecorder()
+	handler.DeleteProduct(w, productRequest(http.MethodDelete, "/products/"+owned, "", other, owned))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.DeleteProduct(w, productRequest(http.MethodDelete, "/products/"+legacy, "", owner, legacy))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.DeleteProduct(w, productRequest(http.MethodDelete, "/products/"+owned, "", owner, owned))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.DeleteProduct(w, productRequest(http.MethodDelete, "/products/"+legacy, "", admin, legacy))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, db.products)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	pkg "github.com/rafaelsouzaribeiro/9-API/pkg/entity"
+)
+
+const dateLayout = "2006-01-02"
+
+// parseProductQuery monta o database.ProductQuery a partir da query string,
+// acumulando um erro por campo inválido
+func parseProductQuery(r *http.Request) (database.ProductQuery, []FieldError) {
+	values := r.URL.Query()
+	page, limit, details := pageParams(r)
+
+	add := func(fieldErr *FieldError) {
+		if fieldErr != nil {
+			details = append(details, *fieldErr)
+		}
+	}
+
+	query := database.ProductQuery{
+		Page:   page,
+		Limit:  limit,
+		Sort:   values.Get("sort"),
+		SortBy: values.Get("sort_by"),
+		Name:   values.Get("name"),
+	}
+
+	switch owner := values.Get("owner_id"); owner {
+	case "":
+	case "me":
+		query.OwnerId, _ = authenticatedUser(r)
+
+		if query.OwnerId == "" {
+			add(&FieldError{Field: "owner_id", Message: "me exige um token com usuário"})
+		}
+	default:
+		if _, err := pkg.ParseId(owner); err != nil {
+			add(&FieldError{Field: "owner_id", Message: "use o id de um usuário ou me"})
+		}
+
+		query.OwnerId = owner
+	}
+
+	if query.Sort != "" && query.Sort != "asc" && query.Sort != "desc" {
+		add(&FieldError{Field: "sort", Message: "use asc ou desc"})
+	}
+
+	if query.SortBy != "" && !database.IsValidSortBy(query.SortBy) {
+		add(&FieldError{Field: "sort_by", Message: "use created_at, name ou price"})
+	}
+
+	var fieldErr *FieldError
+
+	query.MinPrice, fieldErr = priceParam(r, "min_price")
+	add(fieldErr)
+
+	query.MaxPrice, fieldErr = priceParam(r, "max_price")
+	add(fieldErr)
+
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		add(&FieldError{Field: "max_price", Message: "deve ser maior ou igual a min_price"})
+	}
+
+	query.CreatedFrom, fieldErr = timeParam(r, "created_from", false)
+	add(fieldErr)
+
+	query.CreatedTo, fieldErr = timeParam(r, "created_to", true)
+	add(fieldErr)
+
+	if query.CreatedFrom != nil && query.CreatedTo != nil && query.CreatedFrom.After(*query.CreatedTo) {
+		add(&FieldError{Field: "created_to", Message: "deve ser posterior a created_from"})
+	}
+
+	if value := values.Get("cursor"); value != "" {
+		cursor, err := database.DecodeCursor(value)
+
+		if err != nil {
+			add(&FieldError{Field: "cursor", Message: err.Error()})
+		}
+
+		query.Cursor = cursor
+	}
+
+	return query, details
+}
+
+func priceParam(r *http.Request, name string) (*float64, *FieldError) {
+	value := r.URL.Query().Get(name)
+
+	if value == "" {
+		return nil, nil
+	}
+
+	price, err := strconv.ParseFloat(value, 64)
+
+	if err != nil || price < 0 {
+		return nil, &FieldError{Field: name, Message: "deve ser um número maior ou igual a zero"}
+	}
+
+	return &price, nil
+}
+
+// timeParam aceita RFC 3339 ou apenas a data; com endOfDay a data cobre o dia inteiro
+func timeParam(r *http.Request, name string, endOfDay bool) (*time.Time, *FieldError) {
+	value := r.URL.Query().Get(name)
+
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse(dateLayout, value)
+
+	if err != nil {
+		return nil, &FieldError{Field: name, Message: "use o formato 2006-01-02 ou RFC 3339"}
+	}
+
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	return &t, nil
+}
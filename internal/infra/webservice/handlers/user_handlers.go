@@ -2,66 +2,106 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth"
 	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
 	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
 	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
 )
 
-type UserHandlers struct {
-	UserDB database.UserInterface
+// Motivos de falha contados pelo AuthMetrics.LoginFailed
+const (
+	LoginFailureInvalidCredentials = "invalid_credentials"
+	LoginFailureLockedOut          = "locked_out"
+	LoginFailureEmailNotVerified   = "email_not_verified"
+)
+
+type AuthMetrics interface {
+	TokenIssued()
+	LoginFailed(reason string)
 }
 
-type Error struct {
-	Message string `json:"message"`
+type UserHandlers struct {
+	UserDB       database.UserInterface
+	Tokens       *auth.TokenService
+	Passwords    *auth.PasswordService
+	Verification *auth.VerificationService
+	Login        *auth.LoginService
+	APIKeys      *auth.APIKeyService
+	// Opcional; sem ele o login não é contado
+	Metrics AuthMetrics
 }
 
-func NewUserHandler(DB database.UserInterface) *UserHandlers {
+func NewUserHandler(DB database.UserInterface, tokens *auth.TokenService, passwords *auth.PasswordService, verification *auth.VerificationService, login *auth.LoginService, apiKeys *auth.APIKeyService) *UserHandlers {
 	return &UserHandlers{
-		UserDB: DB,
+		UserDB:       DB,
+		Tokens:       tokens,
+		Passwords:    passwords,
+		Verification: verification,
+		Login:        login,
+		APIKeys:      apiKeys,
+		Metrics:      noMetrics{},
 	}
 }
 
+type noMetrics struct{}
+
+func (noMetrics) TokenIssued()       {}
+func (noMetrics) LoginFailed(string) {}
+
 // Create user godoc
 // @Summary      Create user
-// @Description  Create user
+// @Description  Create an unverified user and email the verification link
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        request     body      dto.CreateUserInput  true  "user request"
 // @Success      201
+// @Failure      400         {object}  Error
+// @Failure      409         {object}  Error
+// @Failure      413         {object}  Error
 // @Failure      500         {object}  Error
 // @Router       /users [post]
 func (h *UserHandlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var dtouser dto.CreateUserInput
-	err := json.NewDecoder(r.Body).Decode(&dtouser)
 
-	if err != nil {
-		//log.Println(err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	if !decodeJSON(w, r, &dtouser) {
 		return
 	}
 
 	resp, errs := entity.NewUser(dtouser.Name, dtouser.Email, dtouser.Password)
 
 	if errs != nil {
-		//log.Println(errs)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		validationError(w, r, errs)
 		return
 	}
 
-	err = h.UserDB.Create(resp)
+	err := h.UserDB.WithContext(r.Context()).Create(resp)
+
+	if errors.Is(err, database.ErrEmailAlreadyExists) {
+		WriteError(w, r, http.StatusConflict, CodeConflict, "E-mail já cadastrado", FieldError{
+			Field:   "email",
+			Message: err.Error(),
+		})
+		return
+	}
 
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		err := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(err)
+		internalError(w, r, err)
 		return
 	}
 
+	h.sendVerification(r, resp)
+
 	w.WriteHeader(http.StatusCreated)
 	message := []byte("Criado com sucesso!\n")
 	w.Write(message)
@@ -69,52 +109,437 @@ func (h *UserHandlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 // GetJWT godoc
 // @Summary      Get a user JWT
-// @Description  Get a user JWT
+// @Description  Get a user JWT. Unknown emails and wrong passwords get the same 401. Repeated failures for an email or from an IP lock them out with 429 and a Retry-After header. Unverified accounts get 403 with the email_not_verified code while verification is required.
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        request   body     dto.GetJWTInput  true  "user credentials"
 // @Success      200  {object}  dto.GetJWTOutput
-// @Failure      404  {object}  Error
+// @Failure      400  {object}  Error
+// @Failure      401  {object}  Error
+// @Failure      403  {object}  Error
+// @Failure      429  {object}  Error
 // @Failure      500  {object}  Error
 // @Router       /users/generate_token [post]
 func (u *UserHandlers) GetJwt(w http.ResponseWriter, r *http.Request) {
-	jwt := r.Context().Value("jwt").(jwtauth.JWTAuth)
-	jwtExpiresin := r.Context().Value("jwtExpiresin").(int)
-
 	var user dto.GetJWTInput
-	err := json.NewDecoder(r.Body).Decode(&user)
+
+	if !decodeJSON(w, r, &user) {
+		return
+	}
+
+	resp, err := u.Login.Authenticate(r.Context(), user.Email, user.Password, clientIP(r))
+
+	var locked *auth.LockedError
+
+	if errors.As(err, &locked) {
+		u.Metrics.LoginFailed(LoginFailureLockedOut)
+		tooManyRequests(w, r, locked)
+		return
+	}
+
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		u.Metrics.LoginFailed(LoginFailureInvalidCredentials)
+		WriteError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Credenciais inválidas")
+		return
+	}
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	if !u.Verification.CanLogin(resp) {
+		u.Metrics.LoginFailed(LoginFailureEmailNotVerified)
+		WriteError(w, r, http.StatusForbidden, CodeEmailNotVerified, "Confirme o e-mail antes de entrar")
+		return
+	}
+
+	tokens, err := u.Tokens.Issue(r.Context(), resp)
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	u.Metrics.TokenIssued()
+	writeTokens(w, tokens)
+}
+
+// Refresh godoc
+// @Summary      Refresh a user JWT
+// @Description  Exchange a refresh token for a new access and refresh token pair. Each refresh token can be used once; reusing one revokes the whole session. An account whose email changed must verify it again before refreshing.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.RefreshTokenInput  true  "refresh token"
+// @Success      200  {object}  dto.GetJWTOutput
+// @Failure      400  {object}  Error
+// @Failure      401  {object}  Error
+// @Failure      403  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/refresh [post]
+func (u *UserHandlers) Refresh(w http.ResponseWriter, r *http.Request) {
+	var input dto.RefreshTokenInput
+
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	tokens, err := u.Tokens.Refresh(r.Context(), input.RefreshToken)
+
+	if errors.Is(err, auth.ErrInvalidRefreshToken) || errors.Is(err, auth.ErrRefreshTokenReused) {
+		WriteError(w, r, http.StatusUnauthorized, CodeUnauthorized, err.Error())
+		return
+	}
+
+	if errors.Is(err, auth.ErrEmailNotVerified) {
+		WriteError(w, r, http.StatusForbidden, CodeEmailNotVerified, "Confirme o e-mail antes de entrar")
+		return
+	}
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	writeTokens(w, tokens)
+}
+
+// Logout godoc
+// @Summary      Logout
+// @Description  Revoke the access token used in the request and, when informed, the session of the refresh token
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.LogoutInput  false  "refresh token"
+// @Success      204
+// @Failure      400  {object}  Error
+// @Failure      401  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/logout [post]
+// @Security ApiKeyAuth
+func (u *UserHandlers) Logout(w http.ResponseWriter, r *http.Request) {
+	token, _, _ := jwtauth.FromContext(r.Context())
+
+	var input dto.LogoutInput
+
+	// O corpo é opcional
+	if r.ContentLength != 0 && !decodeJSON(w, r, &input) {
+		return
+	}
+
+	err := u.Tokens.Logout(r.Context(), token.Subject(), token.JwtID(), token.Expiration(), input.RefreshToken)
 
 	if err != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		internalError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateUserRole godoc
+// @Summary      Assign a role
+// @Description  Assign admin, editor or viewer to a user. Requires the users:manage permission. The new role is applied to the user's next token.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id        path     string                true  "user ID" Format(uuid)
+// @Param        request   body     dto.UpdateRoleInput   true  "role"
+// @Success      200  {object}  entity.User
+// @Failure      400  {object}  Error
+// @Failure      401  {object}  Error
+// @Failure      403  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /admin/users/{id}/role [put]
+// @Security ApiKeyAuth
+func (u *UserHandlers) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var input dto.UpdateRoleInput
+
+	if !decodeJSON(w, r, &input) {
 		return
 	}
 
-	resp, err := u.UserDB.FindByEmail(user.Email)
+	user, err := u.UserDB.WithContext(r.Context()).FindById(id)
 
 	if err != nil {
-		http.Error(w, "Not Found", http.StatusFound)
-		err := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(err)
+		if errors.Is(err, database.ErrNotFound) {
+			notFound(w, r, "Usuário não encontrado")
+			return
+		}
+
+		internalError(w, r, err)
+		return
+	}
+
+	// Impede que o último acesso administrativo se perca por engano
+	token, _, _ := jwtauth.FromContext(r.Context())
+
+	if token != nil && token.Subject() == user.Id.String() && input.Role != entity.RoleAdmin {
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Dados inválidos", FieldError{
+			Field:   "role",
+			Message: "Não é possível remover o próprio papel de administrador",
+		})
+		return
+	}
+
+	if err := user.SetRole(input.Role); err != nil {
+		validationError(w, r, err)
 		return
 	}
 
-	if !resp.ValidatePassword(user.Password) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		err := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(err)
+	if err := u.UserDB.WithContext(r.Context()).Update(user); err != nil {
+		internalError(w, r, err)
 		return
 	}
 
-	_, token, _ := jwt.Encode(map[string]interface{}{
-		"sub": resp.Id.String(),
-		"exp": time.Now().Add(time.Second * time.Duration(jwtExpiresin)).Unix(),
-	})
+	writeUser(w, user)
+}
 
-	acessToken := dto.GetJWTOutput{AcessToken: token}
+// GetMe godoc
+// @Summary      Get the authenticated user
+// @Description  Get the account of the token owner
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  entity.User
+// @Failure      401  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/me [get]
+// @Security ApiKeyAuth
+func (u *UserHandlers) GetMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+
+	if !ok {
+		return
+	}
+
+	writeUser(w, user)
+}
+
+// GetToken godoc
+// @Summary      Inspect the access token
+// @Description  Decoded claims of the access token used in the request and the seconds left before it expires
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  dto.TokenClaimsOutput
+// @Failure      401  {object}  Error
+// @Router       /users/me/token [get]
+// @Security ApiKeyAuth
+func (u *UserHandlers) GetToken(w http.ResponseWriter, r *http.Request) {
+	token, _, _ := jwtauth.FromContext(r.Context())
+	_, role := authenticatedUser(r)
+
+	output := dto.TokenClaimsOutput{
+		Subject:   token.Subject(),
+		Role:      role,
+		Issuer:    token.Issuer(),
+		Audience:  token.Audience(),
+		JwtId:     token.JwtID(),
+		IssuedAt:  token.IssuedAt(),
+		NotBefore: token.NotBefore(),
+		ExpiresAt: token.Expiration(),
+		ExpiresIn: int64(math.Max(0, time.Until(token.Expiration()).Seconds())),
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(acessToken)
+	json.NewEncoder(w).Encode(output)
+}
+
+// UpdateMe godoc
+// @Summary      Update the authenticated user
+// @Description  Change the name and/or the email of the token owner. Fields left out are kept. A new email has to be verified again.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.UpdateUserInput  true  "fields to change"
+// @Success      200  {object}  entity.User
+// @Failure      400  {object}  Error
+// @Failure      401  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      409  {object}  Error
+// @Failure      413  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/me [patch]
+// @Security ApiKeyAuth
+func (u *UserHandlers) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	var input dto.UpdateUserInput
+
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	user, ok := u.currentUser(w, r)
+
+	if !ok {
+		return
+	}
+
+	if input.Name != nil {
+		user.Name = strings.TrimSpace(*input.Name)
+	}
 
+	if input.Email != nil {
+		user.ChangeEmail(*input.Email)
+	}
+
+	err := u.UserDB.WithContext(r.Context()).Update(user)
+
+	if errors.Is(err, database.ErrEmailAlreadyExists) {
+		WriteError(w, r, http.StatusConflict, CodeConflict, "E-mail já cadastrado", FieldError{
+			Field:   "email",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	u.sendVerification(r, user)
+	writeUser(w, user)
+}
+
+// DeleteMe godoc
+// @Summary      Delete the authenticated user
+// @Description  Delete the account of the token owner and end its sessions. Products it owned are kept without an owner.
+// @Tags         users
+// @Success      204
+// @Failure      401  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/me [delete]
+// @Security ApiKeyAuth
+func (u *UserHandlers) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+
+	if !ok {
+		return
+	}
+
+	if err := u.UserDB.WithContext(r.Context()).Delete(user.Id.String()); err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	// Os refresh tokens saem junto com o usuário; resta derrubar o access token atual
+	token, _, _ := jwtauth.FromContext(r.Context())
+
+	if err := u.Tokens.Logout(r.Context(), user.Id.String(), token.JwtID(), token.Expiration(), ""); err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUsers godoc
+// @Summary      List users
+// @Description  List users ordered by email, page by page. Requires the users:manage permission.
+// @Tags         admin
+// @Produce      json
+// @Param        page   query     int  false  "page number"  default(1)
+// @Param        limit  query     int  false  "items per page (max 100)"  default(20)
+// @Success      200    {object}  dto.UserListOutput
+// @Header       200    {string}  Link  "first, prev, next and last page links"
+// @Failure      400    {object}  Error
+// @Failure      401    {object}  Error
+// @Failure      403    {object}  Error
+// @Failure      500    {object}  Error
+// @Router       /admin/users [get]
+// @Security ApiKeyAuth
+func (u *UserHandlers) GetUsers(w http.ResponseWriter, r *http.Request) {
+	page, limit, details := pageParams(r)
+
+	if len(details) > 0 {
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Parâmetros inválidos", details...)
+		return
+	}
+
+	users, total, err := u.UserDB.WithContext(r.Context()).FindAll(page, limit)
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	if users == nil {
+		users = []entity.User{}
+	}
+
+	output := dto.UserListOutput{
+		Items:      users,
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages(total, limit),
+	}
+
+	output.Next, output.Prev = pageLinks(w, r, page, output.TotalPages)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// currentUser carrega o dono do token. Um token de conta já removida recebe 404.
+func (u *UserHandlers) currentUser(w http.ResponseWriter, r *http.Request) (*entity.User, bool) {
+	id, _ := authenticatedUser(r)
+	user, err := u.UserDB.WithContext(r.Context()).FindById(id)
+
+	if errors.Is(err, database.ErrNotFound) {
+		notFound(w, r, "Usuário não encontrado")
+		return nil, false
+	}
+
+	if err != nil {
+		internalError(w, r, err)
+		return nil, false
+	}
+
+	return user, true
+}
+
+func writeUser(w http.ResponseWriter, user *entity.User) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// clientIP usa o endereço da conexão. Cabeçalhos como X-Forwarded-For são
+// ignorados porque o cliente poderia trocá-los a cada tentativa.
+// tooManyRequests responde 429 com o Retry-After do bloqueio
+func tooManyRequests(w http.ResponseWriter, r *http.Request, locked *auth.LockedError) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	WriteError(w, r, http.StatusTooManyRequests, CodeTooManyRequests, "Muitas tentativas, tente novamente mais tarde")
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func writeTokens(w http.ResponseWriter, tokens *auth.Tokens) {
+	output := dto.GetJWTOutput{
+		AcessToken:   tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
 }
@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTokenAuth = jwtauth.New("HS256", []byte("secret"), nil)
+
+type fakeUserDB struct {
+	users []*entity.User
+}
+
+func (f *fakeUserDB) Create(user *entity.User) error {
+	if _, err := f.FindByEmail(user.Email); err == nil {
+		return database.ErrEmailAlreadyExists
+	}
+
+	f.users = append(f.users, user)
+	return nil
+}
+
+func (f *fakeUserDB) FindByEmail(email string) (*entity.User, error) {
+	for _, user := range f.users {
+		if user.Email == entity.NormalizeEmail(email) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeUserDB) FindById(id string) (*entity.User, error) {
+	for _, user := range f.users {
+		if user.Id.String() == id {
+			copied := *user
+			return &copied, nil
+		}
+	}
+
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeUserDB) Update(user *entity.User) error {
+	if other, err := f.FindByEmail(user.Email); err == nil && other.Id != user.Id {
+		return database.ErrEmailAlreadyExists
+	}
+
+	for i := range f.users {
+		if f.users[i].Id == user.Id {
+			copied := *user
+			f.users[i] = &copied
+			return nil
+		}
+	}
+
+	return database.ErrNotFound
+}
+
+func (f *fakeUserDB) Delete(id string) error {
+	for i := range f.users {
+		if f.users[i].Id.String() == id {
+			f.users = append(f.users[:i], f.users[i+1:]...)
+			return nil
+		}
+	}
+
+	return database.ErrNotFound
+}
+
+func (f *fakeUserDB) FindAll(page, limit int) ([]entity.User, int64, error) {
+	var users []entity.User
+
+	for _, user := range f.users {
+		users = append(users, *user)
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	start := min((page-1)*limit, len(users))
+
+	return users[start:min(start+limit, len(users))], int64(len(users)), nil
+}
+
+func (f *fakeUserDB) WithContext(ctx context.Context) database.UserInterface {
+	return f
+}
+
+type fakeRevokedTokens struct {
+	jtis map[string]time.Time
+}
+
+func (f *fakeRevokedTokens) Revoke(jti string, expiresAt time.Time) error {
+	f.jtis[jti] = expiresAt
+	return nil
+}
+
+func (f *fakeRevokedTokens) IsRevoked(jti string) (bool, error) {
+	_, ok := f.jtis[jti]
+	return ok, nil
+}
+
+func (f *fakeRevokedTokens) DeleteExpired() (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeRevokedTokens) WithContext(ctx context.Context) database.RevokedTokenInterface {
+	return f
+}
+
+func newTestUser(t *testing.T, db *fakeUserDB, email, role string) *entity.User {
+	user, err := entity.NewUser("Rafael", email, "rafa2024x")
+	assert.NoError(t, err)
+	assert.NoError(t, user.SetRole(role))
+	assert.NoError(t, db.Create(user))
+
+	return user
+}
+
+func newTestLogin(db *fakeUserDB) *auth.LoginService {
+	return auth.NewLoginService(db, auth.NewLoginLimiter(3, time.Minute, time.Hour), auth.NewLoginLimiter(10, time.Minute, time.Hour))
+}
+
+// withToken coloca no contexto o token do usuário, como o jwtauth.Verifier faria
+func withToken(r *http.Request, user *entity.User) *http.Request {
+	token, _, _ := testTokenAuth.Encode(map[string]interface{}{"sub": user.Id.String(), "role": user.Role, "jti": user.Id.String()})
+	return r.WithContext(jwtauth.NewContext(r.Context(), token, nil))
+}
+
+// withURLParam simula o parâmetro de rota preenchido pelo chi
+func withURLParam(r *http.Request, key, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func updateRole(handler *UserHandlers, caller, target *entity.User, role string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPut, "/admin/users/"+target.Id.String()+"/role", strings.NewReader(`{"role":"`+role+`"}`))
+	r = withToken(withURLParam(r, "id", target.Id.String()), caller)
+	w := httptest.NewRecorder()
+	handler.UpdateUserRole(w, r)
+
+	return w
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	db := &fakeUserDB{}
+	admin := newTestUser(t, db, "admin@gmail.com", entity.RoleAdmin)
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler := NewUserHandler(db, nil, nil, noVerification, nil, nil)
+
+	w := updateRole(handler, admin, user, entity.RoleEditor)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body entity.User
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, entity.RoleEditor, body.Role)
+
+	found, _ := db.FindById(user.Id.String())
+	assert.Equal(t, entity.RoleEditor, found.Role)
+}
+
+func TestUpdateUserRoleInvalid(t *testing.T) {
+	db := &fakeUserDB{}
+	admin := newTestUser(t, db, "admin@gmail.com", entity.RoleAdmin)
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler := NewUserHandler(db, nil, nil, noVerification, nil, nil)
+
+	w := updateRole(handler, admin, user, "root")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var body Error
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, "role", body.Details[0].Field)
+
+	// O administrador não pode rebaixar a si mesmo
+	w = updateRole(handler, admin, admin, entity.RoleViewer)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	missing, _ := entity.NewUser("Outro", "outro@gmail.com", "rafa2024x")
+	w = updateRole(handler, admin, missing, entity.RoleEditor)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateUserDuplicatedEmail(t *testing.T) {
+	db := &fakeUserDB{}
+	newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler := NewUserHandler(db, nil, nil, noVerification, nil, nil)
+
+	body := `{"name":"Rafael","email":"Rafael@Gmail.com","password":"rafa2024x"}`
+	w := httptest.NewRecorder()
+	handler.CreateUser(w, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body)))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var out Error
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&out))
+	assert.Equal(t, CodeConflict, out.Code)
+	assert.Equal(t, "email", out.Details[0].Field)
+	assert.Len(t, db.users, 1)
+}
+
+func TestGetMe(t *testing.T) {
+	db := &fakeUserDB{}
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler := NewUserHandler(db, nil, nil, noVerification, nil, nil)
+
+	w := httptest.NewRecorder()
+	handler.GetMe(w, withToken(httptest.NewRequest(http.MethodGet, "/users/me", nil), user))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, user.Id.String(), body["id"])
+	assert.Equal(t, "rafael@gmail.com", body["email"])
+	assert.NotContains(t, body, "password")
+
+	// Token de uma conta que não existe mais
+	missing, _ := entity.NewUser("Outro", "outro@gmail.com", "rafa2024x")
+	w = httptest.NewRecorder()
+	handler.GetMe(w, withToken(httptest.NewRequest(http.MethodGet, "/users/me", nil), missing))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetToken(t *testing.T) {
+	db := &fakeUserDB{}
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleEditor)
+	keys := auth.NewHMACKeySet([]byte("secret"))
+	keys.Validation = auth.TokenValidation{Issuer: "https://api.example.com", Audience: "products"}
+	tokens := &auth.TokenService{JWT: keys, AccessTTL: 5 * time.Minute, Issuer: "https://api.example.com", Audience: "products", RefreshTokens: &fakeRefreshTokens{}}
+	handler := NewUserHandler(db, tokens, nil, noVerification, nil, nil)
+
+	issued, err := tokens.Issue(context.Background(), user)
+	assert.NoError(t, err)
+	token, err := keys.Decode(issued.AccessToken)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/users/me/token", nil)
+	w := httptest.NewRecorder()
+	handler.GetToken(w, r.WithContext(jwtauth.NewContext(r.Context(), token, nil)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body dto.TokenClaimsOutput
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, user.Id.String(), body.Subject)
+	assert.Equal(t, entity.RoleEditor, body.Role)
+	assert.Equal(t, "https://api.example.com", body.Issuer)
+	assert.Equal(t, []string{"products"}, body.Audience)
+	assert.Equal(t, token.JwtID(), body.JwtId)
+	assert.Equal(t, body.IssuedAt, body.NotBefore)
+	assert.Equal(t, body.IssuedAt.Add(5*time.Minute), body.ExpiresAt)
+	assert.InDelta(t, 300, body.ExpiresIn, 2)
+}
+
+func updateMe(handler *UserHandlers, user *entity.User, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPatch, "/users/me", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.UpdateMe(w, withToken(r, user))
+
+	return w
+}
+
+func TestUpdateMe(t *testing.T) {
+	db := &fakeUserDB{}
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler := NewUserHandler(db, nil, nil, noVerification, nil, nil)
+
+	w := updateMe(handler, user, `{"name":"Rafael Souza"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	found, _ := db.FindById(user.Id.String())
+	assert.Equal(t, "Rafael Souza", found.Name)
+	assert.Equal(t, "rafael@gmail.com", found.Email)
+
+	w = updateMe(handler, user, `{"email":"Rafael.Souza@Gmail.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	found, _ = db.FindById(user.Id.String())
+	assert.Equal(t, "Rafael Souza", found.Name)
+	assert.Equal(t, "rafael.souza@gmail.com", found.Email)
+
+	w = updateMe(handler, user, `{"name":"","email":"rafael"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = updateMe(handler, user, `{"name":"   "}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "não pode ficar em branco")
+
+	w = updateMe(handler, user, `{"name":"  Rafael  "}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	found, _ = db.FindById(user.Id.String())
+	assert.Equal(t, "Rafael", found.Name)
+}
+
+func TestUpdateMeDuplicatedEmail(t *testing.T) {
+	db := &fakeUserDB{}
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	newTestUser(t, db, "outro@gmail.com", entity.RoleViewer)
+
+	w := updateMe(NewUserHandler(db, nil, nil, noVerification, nil, nil), user, `{"email":"outro@gmail.com"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestDeleteMe(t *testing.T) {
+	db := &fakeUserDB{}
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	revoked := &fakeRevokedTokens{jtis: map[string]time.Time{}}
+	handler := NewUserHandler(db, &auth.TokenService{RevokedTokens: revoked}, nil, noVerification, nil, nil)
+
+	w := httptest.NewRecorder()
+	handler.DeleteMe(w, withToken(httptest.NewRequest(http.MethodDelete, "/users/me", nil), user))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	_, err := db.FindById(user.Id.String())
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	isRevoked, _ := revoked.IsRevoked(user.Id.String())
+	assert.True(t, isRevoked)
+}
+
+func TestGetUsers(t *testing.T) {
+	db := &fakeUserDB{}
+
+	for _, email := range []string{"c@gmail.com", "a@gmail.com", "b@gmail.com"} {
+		db.users = append(db.users, &entity.User{Email: email})
+	}
+
+	handler := NewUserHandler(db, nil, nil, noVerification, nil, nil)
+
+	w := httptest.NewRecorder()
+	handler.GetUsers(w, httptest.NewRequest(http.MethodGet, "/admin/users?limit=2", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Link"), `rel="next"`)
+
+	var body dto.UserListOutput
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, int64(3), body.Total)
+	assert.Equal(t, 2, body.TotalPages)
+	assert.Equal(t, "a@gmail.com", body.Items[0].Email)
+	assert.Equal(t, "/admin/users?limit=2&page=2", body.Next)
+
+	w = httptest.NewRecorder()
+	handler.GetUsers(w, httptest.NewRequest(http.MethodGet, "/admin/users?page=0", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetJwtDoesNotRevealUnknownEmails(t *testing.T) {
+	db := &fakeUserDB{}
+	newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler := NewUserHandler(db, nil, nil, noVerification, newTestLogin(db), nil)
+
+	unknown, unknownBody := postJSON(handler.GetJwt, httptest.NewRequest(http.MethodPost, "/users/generate_token",
+		strings.NewReader(`{"email":"ninguem@gmail.com","password":"rafa2024x"}`)))
+	wrong, wrongBody := postJSON(handler.GetJwt, httptest.NewRequest(http.MethodPost, "/users/generate_token",
+		strings.NewReader(`{"email":"rafael@gmail.com","password":"errada"}`)))
+
+	assert.Equal(t, http.StatusUnauthorized, unknown.Code)
+	assert.Equal(t, wrong.Code, unknown.Code)
+	assert.Equal(t, wrongBody.Message, unknownBody.Message)
+}
+
+func TestGetJwtLockout(t *testing.T) {
+	db := &fakeUserDB{}
+	newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler := NewUserHandler(db, nil, nil, noVerification, newTestLogin(db), nil)
+
+	for i := 0; i < 3; i++ {
+		w, _ := postJSON(handler.GetJwt, httptest.NewRequest(http.MethodPost, "/users/generate_token",
+			strings.NewReader(`{"email":"rafael@gmail.com","password":"errada"}`)))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	w, body := postJSON(handler.GetJwt, httptest.NewRequest(http.MethodPost, "/users/generate_token",
+		strings.NewReader(`{"email":"rafael@gmail.com","password":"rafa2024x"}`)))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, CodeTooManyRequests, body.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
+
+type fakeAuthMetrics struct {
+	issued   int
+	failures map[string]int
+}
+
+func (m *fakeAuthMetrics) TokenIssued() {
+	m.issued++
+}
+
+func (m *fakeAuthMetrics) LoginFailed(reason string) {
+	m.failures[reason]++
+}
+
+func TestGetJwtMetrics(t *testing.T) {
+	db := &fakeUserDB{}
+	verified := newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	verified.MarkVerified()
+	assert.NoError(t, db.Update(verified))
+	newTestUser(t, db, "novo@gmail.com", entity.RoleViewer)
+
+	handler, _ := newVerificationHandler(db)
+	metrics := &fakeAuthMetrics{failures: map[string]int{}}
+	handler.Metrics = metrics
+
+	w, _ := getJwt(handler, "rafael@gmail.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, _ = getJwt(handler, "novo@gmail.com")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	for i := 0; i < 4; i++ {
+		postJSON(handler.GetJwt, httptest.NewRequest(http.MethodPost, "/users/generate_token",
+			strings.NewReader(`{"email":"novo@gmail.com","password":"errada"}`)))
+	}
+
+	assert.Equal(t, 1, metrics.issued)
+	assert.Equal(t, map[string]int{
+		LoginFailureEmailNotVerified:   1,
+		LoginFailureInvalidCredentials: 3,
+		LoginFailureLockedOut:          1,
+	}, metrics.failures)
+}
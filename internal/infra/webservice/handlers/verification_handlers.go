@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rafaelsouzaribeiro/9-API/internal/dto"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+)
+
+// VerifyEmail godoc
+// @Summary      Verify an email
+// @Description  Confirm the email with the token sent when the account was created or the email changed
+// @Tags         users
+// @Produce      json
+// @Param        token  query     string  true  "verification token"
+// @Success      200    {object}  entity.User
+// @Failure      400    {object}  Error
+// @Failure      500    {object}  Error
+// @Router       /users/verify [get]
+func (u *UserHandlers) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	if token == "" {
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Parâmetros inválidos", FieldError{
+			Field:   "token",
+			Message: "obrigatório",
+		})
+		return
+	}
+
+	user, err := u.Verification.Verify(r.Context(), token)
+
+	if errors.Is(err, auth.ErrInvalidVerificationToken) {
+		WriteError(w, r, http.StatusBadRequest, CodeValidationFailed, "Parâmetros inválidos", FieldError{
+			Field:   "token",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	writeUser(w, user)
+}
+
+// ResendVerification godoc
+// @Summary      Resend the verification email
+// @Description  Send a new verification link. The answer is the same, and as fast, whether the email is registered, already verified or not. Too many requests for the same email or from the same IP are refused for a while.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.ResendVerificationInput  true  "account email"
+// @Success      202
+// @Failure      400  {object}  Error
+// @Failure      413  {object}  Error
+// @Failure      429  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /users/verify/resend [post]
+func (u *UserHandlers) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var input dto.ResendVerificationInput
+
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	err := u.Verification.Resend(r.Context(), input.Email, clientIP(r))
+
+	var locked *auth.LockedError
+
+	if errors.As(err, &locked) {
+		tooManyRequests(w, r, locked)
+		return
+	}
+
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendVerification não falha a requisição: a conta já foi gravada e o link
+// pode ser pedido de novo em /users/verify/resend
+func (u *UserHandlers) sendVerification(r *http.Request, user *entity.User) {
+	if err := u.Verification.Send(user); err != nil {
+		log.Printf("[%s] enviando verificação para %s: %v", middleware.GetReqID(r.Context()), user.Email, err)
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// noVerification libera o login sem confirmar o e-mail e não envia nada
+var noVerification = &auth.VerificationService{}
+
+type fakeNotifier struct {
+	tokens map[string]string
+}
+
+func (n *fakeNotifier) NotifyVerification(user *entity.User, token string) error {
+	n.tokens[user.Email] = token
+	return nil
+}
+
+func newVerificationHandler(db *fakeUserDB) (*UserHandlers, *fakeNotifier) {
+	notifier := &fakeNotifier{tokens: map[string]string{}}
+	verification := auth.NewVerificationService(db, auth.NewVerificationSigner([]byte("secret"), time.Hour), notifier, true)
+	tokens := &auth.TokenService{JWT: testTokenAuth, AccessTTL: time.Minute, RefreshTokens: &fakeRefreshTokens{}}
+
+	return NewUserHandler(db, tokens, nil, verification, newTestLogin(db), nil), notifier
+}
+
+func getJwt(handler *UserHandlers, email string) (*httptest.ResponseRecorder, Error) {
+	r := httptest.NewRequest(http.MethodPost, "/users/generate_token", strings.NewReader(`{"email":"`+email+`","password":"rafa2024x"}`))
+	return postJSON(handler.GetJwt, r)
+}
+
+func TestEmailVerificationFlow(t *testing.T) {
+	db := &fakeUserDB{}
+	handler, notifier := newVerificationHandler(db)
+
+	r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"Rafael","email":"rafael@gmail.com","password":"rafa2024x"}`))
+	w, _ := postJSON(handler.CreateUser, r)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotEmpty(t, notifier.tokens["rafael@gmail.com"])
+
+	w, body := getJwt(handler, "rafael@gmail.com")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, CodeEmailNotVerified, body.Code)
+
+	w = httptest.NewRecorder()
+	handler.VerifyEmail(w, httptest.NewRequest(http.MethodGet, "/users/verify?token="+notifier.tokens["rafael@gmail.com"], nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, _ = getJwt(handler, "rafael@gmail.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestVerifyEmailInvalidToken(t *testing.T) {
+	handler, _ := newVerificationHandler(&fakeUserDB{})
+
+	for _, target := range []string{"/users/verify", "/users/verify?token=abc.def"} {
+		w, body := postJSON(handler.VerifyEmail, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "token", body.Details[0].Field)
+	}
+}
+
+func TestGetJwtWithoutVerification(t *testing.T) {
+	db := &fakeUserDB{}
+	newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler, _ := newVerificationHandler(db)
+	handler.Verification.Required = false
+
+	w, _ := getJwt(handler, "rafael@gmail.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestUpdateMeEmailRequiresVerification(t *testing.T) {
+	db := &fakeUserDB{}
+	user := newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	user.MarkVerified()
+	assert.NoError(t, db.Update(user))
+
+	handler, notifier := newVerificationHandler(db)
+
+	w := updateMe(handler, user, `{"name":"Rafael Souza"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, notifier.tokens)
+
+	w = updateMe(handler, user, `{"email":"novo@gmail.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, notifier.tokens["novo@gmail.com"])
+
+	found, _ := db.FindById(user.Id.String())
+	assert.False(t, found.IsVerified())
+}
+
+func TestResendVerification(t *testing.T) {
+	db := &fakeUserDB{}
+	newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler, notifier := newVerificationHandler(db)
+
+	r := httptest.NewRequest(http.MethodPost, "/users/verify/resend", strings.NewReader(`{"email":"rafael@gmail.com"}`))
+	w, _ := postJSON(handler.ResendVerification, r)
+	handler.Verification.Wait()
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.NotEmpty(t, notifier.tokens["rafael@gmail.com"])
+}
+
+func TestResendVerificationLimits(t *testing.T) {
+	db := &fakeUserDB{}
+	newTestUser(t, db, "rafael@gmail.com", entity.RoleViewer)
+	handler, _ := newVerificationHandler(db)
+	handler.Verification.Limits = auth.NewEmailLimits(auth.NewLoginLimiter(2, time.Minute, time.Hour), auth.NewLoginLimiter(10, time.Minute, time.Hour))
+
+	resend := func(email string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/users/verify/resend", strings.NewReader(`{"email":"`+email+`"}`))
+		w, _ := postJSON(handler.ResendVerification, r)
+		return w
+	}
+
+	// E-mails desconhecidos gastam a cota do mesmo jeito
+	for _, email := range []string{"rafael@gmail.com", "ninguem@gmail.com"} {
+		assert.Equal(t, http.StatusAccepted, resend(email).Code)
+		assert.Equal(t, http.StatusAccepted, resend(email).Code)
+
+		w := resend(email)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	}
+
+	handler.Verification.Wait()
+}
@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/webservice/handlers"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, plain string) (*entity.User, error)
+}
+
+// APIKey aceita o header X-API-Key como alternativa ao Bearer. A chave vira no
+// contexto um token com o "sub" e o "role" do dono, então o Authenticator e o
+// RequirePermission tratam os dois casos igual. Deve vir depois do Verifier;
+// se os dois forem enviados, vale a API key.
+func APIKey(keys APIKeyAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plain := r.Header.Get(APIKeyHeader)
+
+			if plain == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, err := keys.Authenticate(r.Context(), plain)
+
+			if errors.Is(err, auth.ErrInvalidAPIKey) {
+				handlers.WriteError(w, r, http.StatusUnauthorized, handlers.CodeUnauthorized, err.Error())
+				return
+			}
+
+			if errors.Is(err, auth.ErrEmailNotVerified) {
+				handlers.WriteError(w, r, http.StatusForbidden, handlers.CodeEmailNotVerified, "Confirme o e-mail antes de usar a API key")
+				return
+			}
+
+			if err != nil {
+				log.Printf("validando API key: %v", err)
+				handlers.WriteError(w, r, http.StatusInternalServerError, handlers.CodeInternal, "Erro interno")
+				return
+			}
+
+			token := jwt.New()
+			token.Set(jwt.SubjectKey, user.Id.String())
+			token.Set("role", user.Role)
+
+			next.ServeHTTP(w, r.WithContext(jwtauth.NewContext(r.Context(), token, nil)))
+		})
+	}
+}
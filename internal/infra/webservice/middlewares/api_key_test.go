@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAPIKeys map[string]*entity.User
+
+func (f fakeAPIKeys) Authenticate(ctx context.Context, plain string) (*entity.User, error) {
+	if user, ok := f[plain]; ok {
+		return user, nil
+	}
+
+	return nil, auth.ErrInvalidAPIKey
+}
+
+func TestAPIKey(t *testing.T) {
+	editor, _ := entity.NewUser("Rafael", "rafael@gmail.com", "rafa2024x")
+	editor.SetRole(entity.RoleEditor)
+	viewer, _ := entity.NewUser("Ana", "ana@gmail.com", "rafa2024x")
+	keys := fakeAPIKeys{"ak_editor": editor, "ak_viewer": viewer}
+
+	var subject string
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		subject, _ = claims["sub"].(string)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	chain := Verifier(auth.NewHMACKeySet([]byte("secret")))(APIKey(keys)(Authenticator(RequirePermission(entity.PermissionProductsWrite)(ok))))
+
+	serveKey := func(key string) int {
+		r := httptest.NewRequest(http.MethodPost, "/products", nil)
+
+		if key != "" {
+			r.Header.Set(APIKeyHeader, key)
+		}
+
+		w := httptest.NewRecorder()
+		chain.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, serveKey("ak_editor"))
+	assert.Equal(t, editor.Id.String(), subject)
+	assert.Equal(t, http.StatusForbidden, serveKey("ak_viewer"))
+	assert.Equal(t, http.StatusUnauthorized, serveKey("ak_revogada"))
+	assert.Equal(t, http.StatusUnauthorized, serveKey(""))
+}
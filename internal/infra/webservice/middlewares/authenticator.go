@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/webservice/handlers"
+)
+
+// Authenticator substitui o jwtauth.Authenticator para responder com o envelope
+// de erro da API. As claims já foram validadas pelo Verifier, com as regras e a
+// tolerância de relógio do auth.KeySet, e não são conferidas de novo aqui.
+func Authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _, err := jwtauth.FromContext(r.Context())
+
+		if err != nil {
+			handlers.WriteError(w, r, http.StatusUnauthorized, handlers.CodeUnauthorized, err.Error())
+			return
+		}
+
+		if token == nil {
+			handlers.WriteError(w, r, http.StatusUnauthorized, handlers.CodeUnauthorized, http.StatusText(http.StatusUnauthorized))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/webservice/handlers"
+)
+
+// RequirePermission libera a rota apenas quando o papel do claim "role" concede a permissão.
+// Deve vir depois do Authenticator.
+func RequirePermission(permission entity.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, claims, _ := jwtauth.FromContext(r.Context())
+			role, _ := claims["role"].(string)
+
+			if !entity.RoleHasPermission(role, permission) {
+				handlers.WriteError(w, r, http.StatusForbidden, handlers.CodeForbidden, "Permissão insuficiente: "+string(permission))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
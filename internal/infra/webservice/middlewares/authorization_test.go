@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+var tokenAuth = jwtauth.New("HS256", []byte("secret"), nil)
+
+// serve passa a requisição pela mesma cadeia usada no router de produtos
+func serve(claims map[string]interface{}, middleware func(http.Handler) http.Handler) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/products", nil)
+
+	if claims != nil {
+		_, token, _ := tokenAuth.Encode(claims)
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	jwtauth.Verifier(tokenAuth)(Authenticator(middleware(ok))).ServeHTTP(w, r)
+
+	return w
+}
+
+func TestRequirePermission(t *testing.T) {
+	requireWrite := RequirePermission(entity.PermissionProductsWrite)
+
+	assert.Equal(t, http.StatusNoContent, serve(map[string]interface{}{"sub": "1", "role": entity.RoleEditor}, requireWrite).Code)
+	assert.Equal(t, http.StatusNoContent, serve(map[string]interface{}{"sub": "1", "role": entity.RoleAdmin}, requireWrite).Code)
+	assert.Equal(t, http.StatusForbidden, serve(map[string]interface{}{"sub": "1", "role": entity.RoleViewer}, requireWrite).Code)
+
+	// Tokens emitidos antes dos papéis não têm o claim
+	assert.Equal(t, http.StatusForbidden, serve(map[string]interface{}{"sub": "1"}, requireWrite).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(nil, requireWrite).Code)
+}
@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	corsAllowMethods = "GET, POST, PUT, PATCH, DELETE"
+	corsAllowHeaders = "Authorization, Content-Type, " + APIKeyHeader
+	// Por quanto tempo o navegador pode reaproveitar a resposta do preflight
+	corsMaxAge = 600
+)
+
+// CORSOrigins guarda as origens aceitas pelo CORS. Set troca a lista com o
+// servidor no ar; "*" aceita qualquer origem.
+type CORSOrigins struct {
+	allowed atomic.Pointer[map[string]bool]
+}
+
+func NewCORSOrigins(origins []string) *CORSOrigins {
+	o := &CORSOrigins{}
+	o.Set(origins)
+
+	return o
+}
+
+func (o *CORSOrigins) Set(origins []string) {
+	allowed := make(map[string]bool, len(origins))
+
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+
+	o.allowed.Store(&allowed)
+}
+
+func (o *CORSOrigins) Allowed(origin string) bool {
+	allowed := *o.allowed.Load()
+	return allowed["*"] || allowed[origin]
+}
+
+// CORS libera as origens de origins para chamar a API do navegador e responde
+// o preflight sem passar pelas rotas. Origens fora da lista não recebem
+// cabeçalho nenhum, e o navegador bloqueia a resposta.
+func CORS(origins *CORSOrigins) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+
+			// A resposta muda com a origem, inclusive para quem não é aceito
+			w.Header().Add("Vary", "Origin")
+
+			if origin == "" || !origins.Allowed(origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
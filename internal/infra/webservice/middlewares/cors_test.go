@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	origins := NewCORSOrigins([]string{"https://app.example.com"})
+	handler := CORS(origins)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method, origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/products", nil)
+		r.Header.Set("Origin", origin)
+
+		if method == http.MethodOptions {
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := serve(http.MethodGet, "https://app.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = serve(http.MethodOptions, "https://app.example.com")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), APIKeyHeader)
+
+	w = serve(http.MethodGet, "https://outro.example.com")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	// A troca da lista vale para a próxima requisição
+	origins.Set([]string{"https://outro.example.com"})
+	assert.Empty(t, serve(http.MethodGet, "https://app.example.com").Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "https://outro.example.com", serve(http.MethodGet, "https://outro.example.com").Header().Get("Access-Control-Allow-Origin"))
+
+	origins.Set([]string{"*"})
+	assert.Equal(t, "https://qualquer.example.com", serve(http.MethodGet, "https://qualquer.example.com").Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var out bytes.Buffer
+	level := new(slog.LevelVar)
+	handler := RequestLogger(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: level})))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	serve := func(path string) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	serve("/products")
+	assert.Contains(t, out.String(), "level=INFO")
+	assert.Contains(t, out.String(), "path=/products status=200")
+
+	// Com warn só as falhas aparecem, e a troca vale na hora
+	out.Reset()
+	level.Set(slog.LevelWarn)
+	serve("/products")
+	assert.Empty(t, out.String())
+
+	serve("/missing")
+	assert.Contains(t, out.String(), "level=WARN")
+	assert.Contains(t, out.String(), "status=404")
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/webservice/handlers"
+)
+
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// RejectRevokedTokens recusa access tokens cujo jti está na denylist.
+// Deve vir depois do Authenticator, que garante um token válido no contexto.
+func RejectRevokedTokens(checker RevocationChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, _ := jwtauth.FromContext(r.Context())
+
+			if token == nil || token.JwtID() == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			revoked, err := checker.IsRevoked(r.Context(), token.JwtID())
+
+			if err != nil {
+				log.Printf("verificando denylist: %v", err)
+				handlers.WriteError(w, r, http.StatusInternalServerError, handlers.CodeInternal, "Erro interno")
+				return
+			}
+
+			if revoked {
+				handlers.WriteError(w, r, http.StatusUnauthorized, handlers.CodeUnauthorized, "Token revogado")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import "net/http"
+
+// SkipPaths aplica mw a todas as rotas menos às de paths, comparadas com o
+// caminho exato. Serve para tirar do log as sondas que rodam a cada segundo.
+func SkipPaths(mw func(http.Handler) http.Handler, paths ...string) func(http.Handler) http.Handler {
+	skip := make(map[string]bool, len(paths))
+
+	for _, path := range paths {
+		skip[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+type TokenDecoder interface {
+	Decode(tokenString string) (jwt.Token, error)
+}
+
+// Verifier faz o papel do jwtauth.Verifier para qualquer TokenDecoder, como o
+// auth.KeySet que escolhe a chave pelo kid. O token e o erro vão para o mesmo
+// contexto do jwtauth, então o Authenticator e os handlers não mudam.
+func Verifier(decoder TokenDecoder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := verifyRequest(decoder, r)
+			next.ServeHTTP(w, r.WithContext(jwtauth.NewContext(r.Context(), token, err)))
+		})
+	}
+}
+
+func verifyRequest(decoder TokenDecoder, r *http.Request) (jwt.Token, error) {
+	tokenString := jwtauth.TokenFromHeader(r)
+
+	if tokenString == "" {
+		tokenString = jwtauth.TokenFromCookie(r)
+	}
+
+	if tokenString == "" {
+		return nil, jwtauth.ErrNoTokenFound
+	}
+
+	token, err := decoder.Decode(tokenString)
+
+	if err != nil {
+		return nil, jwtauth.ErrorReason(err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rafaelsouzaribeiro/9-API/internal/infra/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifier(t *testing.T) {
+	keys := auth.NewHMACKeySet([]byte("secret"))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	chain := Verifier(keys)(Authenticator(ok))
+
+	serveToken := func(token string) int {
+		r := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		w := httptest.NewRecorder()
+		chain.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	_, valid, _ := keys.Encode(map[string]interface{}{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()})
+	_, expired, _ := keys.Encode(map[string]interface{}{"sub": "1", "exp": time.Now().Add(-time.Minute).Unix()})
+	_, foreign, _ := auth.NewHMACKeySet([]byte("outro")).Encode(map[string]interface{}{"sub": "1"})
+
+	assert.Equal(t, http.StatusNoContent, serveToken(valid))
+	assert.Equal(t, http.StatusUnauthorized, serveToken(expired))
+	assert.Equal(t, http.StatusUnauthorized, serveToken(foreign))
+	assert.Equal(t, http.StatusUnauthorized, serveToken(""))
+}
+
+func TestVerifierChecksIssuerAndAudience(t *testing.T) {
+	keys := auth.NewHMACKeySet([]byte("secret"))
+	keys.Validation = auth.TokenValidation{Issuer: "https://api.example.com", Audience: "products"}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	chain := Verifier(keys)(Authenticator(ok))
+
+	serveClaims := func(claims map[string]interface{}) int {
+		_, token, _ := keys.Encode(claims)
+		r := httptest.NewRequest(http.MethodGet, "/products", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		chain.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	now := time.Now().Unix()
+	assert.Equal(t, http.StatusNoContent, serveClaims(map[string]interface{}{"sub": "1", "iss": "https://api.example.com", "aud": "products", "iat": now, "nbf": now}))
+	assert.Equal(t, http.StatusUnauthorized, serveClaims(map[string]interface{}{"sub": "1", "iss": "https://api.example.com", "aud": "outra-api"}))
+	assert.Equal(t, http.StatusUnauthorized, serveClaims(map[string]interface{}{"sub": "1", "aud": "products"}))
+}
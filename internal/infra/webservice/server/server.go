@@ -0,0 +1,82 @@
+// Package server cuida do ciclo de vida do http.Server: timeouts, sinais e o
+// encerramento que espera as requisições em andamento.
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Config traz os limites do servidor, em tempo e em bytes de cabeçalho
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+}
+
+func New(cfg Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// Run atende em listener até ctx ser cancelado e então para de aceitar
+// conexões, esperando as requisições em andamento por até shutdownTimeout.
+// Erros ao subir o servidor são devolvidos na hora.
+func Run(ctx context.Context, srv *http.Server, listener net.Listener, shutdownTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("encerrando o servidor, aguardando até %s pelas requisições em andamento", shutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Prazo esgotado: as conexões que sobraram são fechadas à força
+		srv.Close()
+		return err
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// ListenAndRun abre a porta de srv.Addr e chama Run. Falhar ao abrir a porta é
+// um erro de inicialização, devolvido antes de qualquer requisição.
+func ListenAndRun(ctx context.Context, srv *http.Server, shutdownTimeout time.Duration) error {
+	listener, err := net.Listen("tcp", srv.Addr)
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("servidor ouvindo em %s", listener.Addr())
+
+	return Run(ctx, srv, listener, shutdownTimeout)
+}
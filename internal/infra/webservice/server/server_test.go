@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listen(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return listener
+}
+
+func TestNewAppliesLimits(t *testing.T) {
+	srv := New(Config{
+		Addr:              ":8080",
+		ReadTimeout:       time.Second,
+		ReadHeaderTimeout: 2 * time.Second,
+		WriteTimeout:      3 * time.Second,
+		IdleTimeout:       4 * time.Second,
+		MaxHeaderBytes:    1 << 16,
+	}, http.NotFoundHandler())
+
+	assert.Equal(t, ":8080", srv.Addr)
+	assert.Equal(t, time.Second, srv.ReadTimeout)
+	assert.Equal(t, 2*time.Second, srv.ReadHeaderTimeout)
+	assert.Equal(t, 3*time.Second, srv.WriteTimeout)
+	assert.Equal(t, 4*time.Second, srv.IdleTimeout)
+	assert.Equal(t, 1<<16, srv.MaxHeaderBytes)
+}
+
+func TestRunDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	srv := New(Config{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+
+	listener := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- Run(ctx, srv, listener, time.Second)
+	}()
+
+	response := make(chan string, 1)
+
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+
+	<-started
+	cancel()
+
+	// A requisição em andamento termina mesmo com o encerramento pedido
+	assert.Equal(t, "ok", <-response)
+	assert.NoError(t, <-done)
+
+	// E novas conexões são recusadas
+	_, err := http.Get("http://" + listener.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestRunGivesUpAfterShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	srv := New(Config{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	listener := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- Run(ctx, srv, listener, 50*time.Millisecond)
+	}()
+
+	go http.Get("http://" + listener.Addr().String())
+
+	<-started
+	cancel()
+	assert.ErrorIs(t, <-done, context.DeadlineExceeded)
+}
+
+func TestListenAndRunReportsStartupErrors(t *testing.T) {
+	busy := listen(t)
+	defer busy.Close()
+
+	srv := New(Config{Addr: busy.Addr().String()}, http.NotFoundHandler())
+	err := ListenAndRun(context.Background(), srv, time.Second)
+	assert.Error(t, err)
+}